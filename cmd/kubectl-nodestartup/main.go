@@ -0,0 +1,232 @@
+// Command kubectl-nodestartup inspects and operates startup gating. Installed on
+// $PATH it runs as "kubectl nodestartup".
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/user"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/duration"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+
+	startup "github.com/zhangchl007/nodetaintshandler/pkg/startup"
+)
+
+const usage = `Usage: kubectl nodestartup [flags] <command>
+
+Commands:
+  status [node...]       List gated nodes and what they are waiting for
+  release <node>         Remove the startup taint now (recorded in startup.k8s.io/releasedBy)
+  regate <node>          Re-apply the startup taint
+  backfill --dry-run     Show nodes the controller's backfill would taint
+
+Flags:
+`
+
+type options struct {
+	kubeconfig    string
+	kubeContext   string
+	configPath    string
+	requiredConds string
+	initPodCheck  bool
+	initRetries   int
+	taintKey      string
+	valueMatch    string
+}
+
+func main() {
+	opts := options{}
+	fs := flag.NewFlagSet("kubectl-nodestartup", flag.ExitOnError)
+	fs.StringVar(&opts.kubeconfig, "kubeconfig", "", "Path to a kubeconfig (default: $KUBECONFIG / ~/.kube/config)")
+	fs.StringVar(&opts.kubeContext, "context", "", "kubeconfig context to use")
+	fs.StringVar(&opts.configPath, "config", "", "Readiness policy file, as passed to the controller")
+	fs.StringVar(&opts.requiredConds, "required-node-conditions", "", "As passed to the controller")
+	fs.BoolVar(&opts.initPodCheck, "init-pod-check", true, "As passed to the controller")
+	fs.IntVar(&opts.initRetries, "init-retries", 3, "As passed to the controller")
+	fs.StringVar(&opts.taintKey, "taint-key", startup.TaintKey, "As passed to the controller")
+	fs.StringVar(&opts.valueMatch, "taint-value-match", string(startup.TaintValueAny), "As passed to the controller")
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), usage)
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(os.Args[1:])
+	if fs.NArg() == 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	if err := startup.SetTaintKey(opts.taintKey); err != nil {
+		fatal(err)
+	}
+	if err := startup.SetTaintValueMatch(startup.TaintValueMatch(opts.valueMatch)); err != nil {
+		fatal(err)
+	}
+
+	rules := clientcmd.NewDefaultClientConfigLoadingRules()
+	rules.ExplicitPath = opts.kubeconfig
+	cfg, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules, &clientcmd.ConfigOverrides{CurrentContext: opts.kubeContext}).ClientConfig()
+	if err != nil {
+		fatal(err)
+	}
+	client, err := kubernetes.NewForConfig(cfg)
+	if err != nil {
+		fatal(err)
+	}
+	if err := run(context.Background(), client, opts, fs.Args(), os.Stdout); err != nil {
+		fatal(err)
+	}
+}
+
+func fatal(err error) {
+	fmt.Fprintln(os.Stderr, "error:", err)
+	os.Exit(1)
+}
+
+func run(ctx context.Context, client kubernetes.Interface, opts options, args []string, out io.Writer) error {
+	ctrlOpts, _, err := startup.ReadinessOptions(opts.configPath, opts.requiredConds, opts.initPodCheck)
+	if err != nil {
+		return err
+	}
+	ctrl := startup.NewController(client, append(ctrlOpts, startup.WithInitRetries(opts.initRetries))...)
+
+	cmd, rest := args[0], args[1:]
+	switch cmd {
+	case "status":
+		return status(ctx, client, ctrl, rest, out)
+	case "release":
+		if len(rest) != 1 {
+			return errors.New("usage: release <node>")
+		}
+		by := whoAmI(ctx, client)
+		changed, err := startup.Release(ctx, client, rest[0], by)
+		if err != nil {
+			return err
+		}
+		if !changed {
+			fmt.Fprintf(out, "node/%s is not gated\n", rest[0])
+			return nil
+		}
+		fmt.Fprintf(out, "node/%s released by %s\n", rest[0], by)
+	case "regate":
+		if len(rest) != 1 {
+			return errors.New("usage: regate <node>")
+		}
+		changed, err := ctrl.Regate(ctx, rest[0])
+		if err != nil {
+			return err
+		}
+		if !changed {
+			fmt.Fprintf(out, "node/%s is already gated\n", rest[0])
+			return nil
+		}
+		fmt.Fprintf(out, "node/%s re-gated\n", rest[0])
+	case "backfill":
+		bf := flag.NewFlagSet("backfill", flag.ContinueOnError)
+		dryRun := bf.Bool("dry-run", false, "Only list the nodes that would be tainted")
+		if err := bf.Parse(rest); err != nil {
+			return err
+		}
+		if !*dryRun {
+			return errors.New("backfill only supports --dry-run; set STARTUP_BACKFILL=1 on the controller to apply")
+		}
+		nodes, err := ctrl.Backfill(ctx, true)
+		if err != nil {
+			return err
+		}
+		if len(nodes) == 0 {
+			fmt.Fprintln(out, "No nodes would be backfilled")
+		}
+		for _, n := range nodes {
+			fmt.Fprintf(out, "node/%s would be tainted (dry run)\n", n)
+		}
+	default:
+		return fmt.Errorf("unknown command %q", cmd)
+	}
+	return nil
+}
+
+// status prints gated nodes with the reasons their readiness policy reports.
+func status(ctx context.Context, client kubernetes.Interface, ctrl *startup.Controller, names []string, out io.Writer) error {
+	var nodes []corev1.Node
+	if len(names) > 0 {
+		for _, name := range names {
+			n, err := client.CoreV1().Nodes().Get(ctx, name, metav1.GetOptions{})
+			if err != nil {
+				return err
+			}
+			nodes = append(nodes, *n)
+		}
+	} else {
+		list, err := client.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return err
+		}
+		nodes = list.Items
+	}
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].Name < nodes[j].Name })
+
+	tw := tabwriter.NewWriter(out, 0, 4, 3, ' ', 0)
+	fmt.Fprintln(tw, "NODE\tAGE\tPOLICY\tFAILURES\tPENDING")
+	gated := 0
+	for i := range nodes {
+		n := &nodes[i]
+		if !startup.HasStartupTaint(n) {
+			if len(names) > 0 {
+				fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%s\n", n.Name, age(n), ctrl.PolicyFor(n).Name, startup.InitFailureCount(n), "<released>")
+			}
+			continue
+		}
+		gated++
+		ready, pending, err := ctrl.Evaluate(n)
+		reason := strings.Join(pending, "; ")
+		switch {
+		case startup.NodeOverride(n) == startup.OverrideHold:
+			reason = fmt.Sprintf("held (%s=true)", startup.HoldAnnotation)
+		case err != nil:
+			reason = "error: " + err.Error()
+		case ready:
+			reason = "<none, release pending>"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%s\n", n.Name, age(n), ctrl.PolicyFor(n).Name, startup.InitFailureCount(n), reason)
+	}
+	if gated == 0 && len(names) == 0 {
+		fmt.Fprintln(out, "No gated nodes")
+		return nil
+	}
+	return tw.Flush()
+}
+
+// age is how long the node has been gated (startup.k8s.io/taintedAt, else its creation).
+func age(n *corev1.Node) string {
+	since := startup.GatedSince(n)
+	if since.IsZero() {
+		return "<unknown>"
+	}
+	return duration.HumanDuration(time.Since(since))
+}
+
+// whoAmI names the operator for the audit annotation: the apiserver's view of
+// the caller when available, else the local user.
+func whoAmI(ctx context.Context, client kubernetes.Interface) string {
+	review, err := client.AuthenticationV1().SelfSubjectReviews().Create(ctx, &authenticationv1.SelfSubjectReview{}, metav1.CreateOptions{})
+	if err == nil && review.Status.UserInfo.Username != "" {
+		return review.Status.UserInfo.Username
+	}
+	if u, err := user.Current(); err == nil {
+		return "local:" + u.Username
+	}
+	return "unknown"
+}
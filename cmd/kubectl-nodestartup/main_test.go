@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	startup "github.com/zhangchl007/nodetaintshandler/pkg/startup"
+)
+
+var defaultOpts = options{initPodCheck: true, initRetries: 3}
+
+func runCmd(t *testing.T, client *fake.Clientset, args ...string) string {
+	t.Helper()
+	var out bytes.Buffer
+	if err := run(context.TODO(), client, defaultOpts, args, &out); err != nil {
+		t.Fatalf("%v: %v", args, err)
+	}
+	return out.String()
+}
+
+func TestStatus_ShowsPendingComponents(t *testing.T) {
+	gated := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "gated"},
+		Spec:       corev1.NodeSpec{Taints: []corev1.Taint{startup.StartupTaint}},
+	}
+	released := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "released"}}
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "init-x", Namespace: "kube-system", Labels: map[string]string{startup.StartPodLabelKey: startup.StartPodLabelValue}},
+		Spec:       corev1.PodSpec{NodeName: "gated"},
+		Status:     corev1.PodStatus{ContainerStatuses: []corev1.ContainerStatus{{Name: "init"}}},
+	}
+	out := runCmd(t, fake.NewSimpleClientset(gated, released, pod), "status")
+	if !strings.Contains(out, "gated") || !strings.Contains(out, "kube-system/init-x: container init not ready") {
+		t.Fatalf("unexpected status output:\n%s", out)
+	}
+	if strings.Contains(out, "released") {
+		t.Fatalf("released node listed:\n%s", out)
+	}
+}
+
+func TestStatus_HeldAndGatedAge(t *testing.T) {
+	regated := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              "regated",
+			CreationTimestamp: metav1.NewTime(time.Now().Add(-30 * 24 * time.Hour)),
+			Annotations:       map[string]string{startup.NodeTaintedAtAnnotation: time.Now().Add(-5 * time.Minute).UTC().Format(time.RFC3339)},
+		},
+		Spec: corev1.NodeSpec{Taints: []corev1.Taint{startup.StartupTaint}},
+	}
+	held := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "held", Annotations: map[string]string{startup.HoldAnnotation: "true"}},
+		Spec:       corev1.NodeSpec{Taints: []corev1.Taint{startup.StartupTaint}},
+	}
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "init-h", Namespace: "kube-system", Labels: map[string]string{startup.StartPodLabelKey: startup.StartPodLabelValue}},
+		Spec:       corev1.PodSpec{NodeName: "held"},
+		Status:     corev1.PodStatus{Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue}}},
+	}
+	out := runCmd(t, fake.NewSimpleClientset(regated, held, pod), "status")
+	if !strings.Contains(out, "held (startup.k8s.io/hold=true)") || strings.Contains(out, "release pending") {
+		t.Fatalf("held node not reported as held:\n%s", out)
+	}
+	for _, line := range strings.Split(out, "\n") {
+		if strings.HasPrefix(line, "regated") && !strings.Contains(line, " 5m") {
+			t.Fatalf("age should count from taintedAt: %q", line)
+		}
+	}
+}
+
+func TestReleaseAndRegate(t *testing.T) {
+	client := fake.NewSimpleClientset(&corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "n1"},
+		Spec:       corev1.NodeSpec{Taints: []corev1.Taint{startup.StartupTaint}},
+	})
+	if out := runCmd(t, client, "release", "n1"); !strings.Contains(out, "node/n1 released by") {
+		t.Fatalf("release output %q", out)
+	}
+	n, _ := client.CoreV1().Nodes().Get(context.TODO(), "n1", metav1.GetOptions{})
+	if startup.HasStartupTaint(n) || n.Annotations[startup.NodeReleasedByAnnotation] == "" {
+		t.Fatalf("release not applied: %v %v", n.Spec.Taints, n.Annotations)
+	}
+	if out := runCmd(t, client, "regate", "n1"); !strings.Contains(out, "re-gated") {
+		t.Fatalf("regate output %q", out)
+	}
+	if out := runCmd(t, client, "status"); !strings.Contains(out, "n1") {
+		t.Fatalf("re-gated node missing from status:\n%s", out)
+	}
+}
+
+func TestBackfill_RequiresDryRun(t *testing.T) {
+	client := fake.NewSimpleClientset(&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "idle"}})
+	if err := run(context.TODO(), client, defaultOpts, []string{"backfill"}, &bytes.Buffer{}); err == nil {
+		t.Fatalf("expected error without --dry-run")
+	}
+	if out := runCmd(t, client, "backfill", "--dry-run"); !strings.Contains(out, "node/idle would be tainted") {
+		t.Fatalf("backfill output %q", out)
+	}
+}
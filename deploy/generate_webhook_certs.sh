@@ -1,7 +1,8 @@
 #!/usr/bin/env bash
 #
 # Generate a dedicated CA + server certificate for the webhook Service,
-# create/update the TLS Secret, and patch the MutatingWebhookConfiguration caBundle.
+# create/update the TLS Secret, publish the CA in a ConfigMap for init agents,
+# and patch the MutatingWebhookConfiguration caBundle.
 #
 # Default SANs:
 #   node-startup-webhook.kube-system.svc
@@ -16,7 +17,9 @@
 #       --namespace kube-system \
 #       --service node-startup-webhook \
 #       --secret node-startup-webhook-tls \
+#       --ca-configmap node-startup-webhook-ca \
 #       --webhook node-startup-taint \
+#       [--guard-webhook node-startup-taint-guard] \
 #       [--force]
 #
 # If --force is set existing key/cert files are overwritten and Secret re-created.
@@ -26,7 +29,9 @@ set -euo pipefail
 NAMESPACE="kube-system"
 SERVICE="node-startup-webhook"
 SECRET="node-startup-webhook-tls"
+CA_CONFIGMAP="node-startup-webhook-ca"
 WEBHOOK_CFG="node-startup-taint"
+GUARD_CFG="node-startup-taint-guard"
 FORCE=0
 OUTDIR="certs"
 DAYS=3650
@@ -36,7 +41,9 @@ while [[ $# -gt 0 ]]; do
     --namespace) NAMESPACE="$2"; shift 2 ;;
     --service) SERVICE="$2"; shift 2 ;;
     --secret) SECRET="$2"; shift 2 ;;
+    --ca-configmap) CA_CONFIGMAP="$2"; shift 2 ;;
     --webhook) WEBHOOK_CFG="$2"; shift 2 ;;
+    --guard-webhook) GUARD_CFG="$2"; shift 2 ;;
     --outdir) OUTDIR="$2"; shift 2 ;;
     --days) DAYS="$2"; shift 2 ;;
     --force) FORCE=1; shift ;;
@@ -63,6 +70,7 @@ echo "==> Configuration"
 echo "Namespace:   ${NAMESPACE}"
 echo "Service:     ${SERVICE}"
 echo "Secret:      ${SECRET}"
+echo "CA ConfigMap: ${CA_CONFIGMAP}"
 echo "WebhookCfg:  ${WEBHOOK_CFG}"
 echo "Output dir:  ${PWD}"
 echo
@@ -136,6 +144,10 @@ else
   echo "Secret exists (use --force to recreate)"
 fi
 
+echo "==> Publishing CA in ConfigMap ${CA_CONFIGMAP}"
+kubectl -n "${NAMESPACE}" create configmap "${CA_CONFIGMAP}" --from-file=ca.crt="${CA_CRT}" \
+  --dry-run=client -o yaml | kubectl apply -f -
+
 echo "==> Generating base64 CA bundle"
 # portable base64 (Linux: -w0, macOS: no -w; fallback)
 if base64 -w0 < /dev/null >/dev/null 2>&1; then
@@ -154,6 +166,11 @@ else
   kubectl patch mutatingwebhookconfiguration "${WEBHOOK_CFG}" --type='json' -p="${PATCH}"
 fi
 
+if kubectl get validatingwebhookconfiguration "${GUARD_CFG}" >/dev/null 2>&1; then
+  echo "==> Patching ValidatingWebhookConfiguration ${GUARD_CFG}"
+  kubectl patch validatingwebhookconfiguration "${GUARD_CFG}" --type='json' -p="${PATCH}"
+fi
+
 echo
 echo "==> Done"
 echo "Summary:"
@@ -161,6 +178,7 @@ echo "  CA cert:      ${OUTDIR}/${CA_CRT}"
 echo "  Server cert:  ${OUTDIR}/${SRV_CRT}"
 echo "  Server key:   ${OUTDIR}/${SRV_KEY}"
 echo "  Secret:       ${SECRET} (namespace: ${NAMESPACE})"
+echo "  CA ConfigMap: ${CA_CONFIGMAP} (namespace: ${NAMESPACE})"
 echo "  Webhook cfg:  ${WEBHOOK_CFG} patched (if existed)"
 echo
 echo "If needed, update caBundle in manifest [deploy/deployment.yaml] manually with:"
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/zhangchl007/nodetaintshandler/pkg/certs"
+	"github.com/zhangchl007/nodetaintshandler/pkg/healthz"
+	"github.com/zhangchl007/nodetaintshandler/pkg/leader"
+	"github.com/zhangchl007/nodetaintshandler/pkg/lifecycle"
+	startup "github.com/zhangchl007/nodetaintshandler/pkg/startup"
+	"k8s.io/client-go/kubernetes"
+)
+
+const apiserverCheckTimeout = 2 * time.Second
+
+// healthChecks assembles the named sub-checks behind /healthz and /readyz.
+type healthChecks struct {
+	mgr     *lifecycle.Manager
+	ctrl    *startup.Controller
+	client  kubernetes.Interface
+	cert    *atomic.Pointer[tls.Certificate] // holds the cert once the webhook loaded it
+	elector *leader.Elector                  // nil when leader election is disabled
+}
+
+// liveness only fails for conditions a restart fixes (stale held lease).
+func (h healthChecks) liveness() []healthz.Checker {
+	checks := []healthz.Checker{healthz.Ping}
+	if h.elector != nil {
+		checks = append(checks, h.elector)
+	}
+	return checks
+}
+
+func (h healthChecks) readiness() []healthz.Checker {
+	checks := []healthz.Checker{
+		healthz.Ping,
+		healthz.NamedCheck("shutdown", func(*http.Request) error {
+			if !h.mgr.Ready() {
+				return errors.New("not started or shutting down")
+			}
+			return nil
+		}),
+		healthz.NamedCheck("informer-sync", func(*http.Request) error {
+			if !h.ctrl.HasSynced() {
+				return errors.New("node/pod caches not synced")
+			}
+			return nil
+		}),
+		healthz.NamedCheck("tls-cert", func(*http.Request) error {
+			return certs.CheckValid(h.cert.Load(), time.Now())
+		}),
+		healthz.NamedCheck("apiserver", func(r *http.Request) error {
+			ctx, cancel := context.WithTimeout(r.Context(), apiserverCheckTimeout)
+			defer cancel()
+			return h.client.Discovery().RESTClient().Get().AbsPath("/readyz").Do(ctx).Error()
+		}),
+	}
+	if h.elector != nil {
+		checks = append(checks, h.elector)
+	}
+	return checks
+}
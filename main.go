@@ -4,114 +4,342 @@ import (
 	"context"
 	"crypto/tls"
 	"errors"
+	"flag"
+	"fmt"
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"sync/atomic"
 	"syscall"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/klog/v2"
 
+	"github.com/zhangchl007/nodetaintshandler/pkg/certs"
+	"github.com/zhangchl007/nodetaintshandler/pkg/healthz"
+	"github.com/zhangchl007/nodetaintshandler/pkg/leader"
+	"github.com/zhangchl007/nodetaintshandler/pkg/lifecycle"
+	"github.com/zhangchl007/nodetaintshandler/pkg/report"
 	startup "github.com/zhangchl007/nodetaintshandler/pkg/startup"
 	"github.com/zhangchl007/nodetaintshandler/pkg/webhook"
 	"k8s.io/client-go/kubernetes"
-	"k8s.io/client-go/rest"
 )
 
 const (
-	certPath = "/tls/tls.crt"
-	keyPath  = "/tls/tls.key"
+	defaultCertPath = "/tls/tls.crt"
+	defaultKeyPath  = "/tls/tls.key"
 )
 
-var ready atomic.Bool
+// Set at build time (see Makefile LDFLAGS).
+var (
+	version   = "dev"
+	buildTime = "unknown"
+)
+
+// Process exit codes.
+const (
+	exitOK              = 0
+	exitComponentFailed = 1
+	exitShutdownTimeout = 2
+)
+
+type options struct {
+	kubeconfig      string
+	kubeContext     string
+	dev             bool
+	webhookAddr     string
+	certPath        string
+	keyPath         string
+	workers         int
+	shutdownTimeout time.Duration
+	shutdownDelay   time.Duration
+	leaderElect     bool
+	leaderElectNS   string
+	leaderElectName string
+	validateUpdates bool
+	controllerUser  string
+	removalAllow    string
+	configPath      string
+	initRetries     int
+	initPodTmpl     string
+	componentReport bool
+	reportAudiences string
+	reportAccounts  string
+	requiredConds   string
+	initPodCheck    bool
+	releaseRate     float64
+	releaseBurst    int
+	breaker         startup.BreakerConfig
+	taintKey        string
+	taintValueMatch string
+	errorPolicy     string
+	webhookLimits   webhook.Limits
+	clientCAFile    string
+	requireClient   bool
+	healthAddr      string
+}
 
 func main() {
+	opts := options{}
+	klog.InitFlags(nil)
+	flag.StringVar(&opts.kubeconfig, "kubeconfig", "", "Path to a kubeconfig (default: in-cluster, then $KUBECONFIG / ~/.kube/config)")
+	flag.StringVar(&opts.kubeContext, "context", "", "kubeconfig context to use")
+	flag.BoolVar(&opts.dev, "dev", false, "Local dev mode: serve webhook on localhost with an in-memory self-signed cert")
+	flag.StringVar(&opts.webhookAddr, "webhook-addr", "", "Webhook listen address (default :8443, 127.0.0.1:8443 in dev mode)")
+	flag.StringVar(&opts.healthAddr, "health-addr", "", "Plain-HTTP listen address for /healthz, /readyz and /metrics (default :8080, 127.0.0.1:8080 in dev mode)")
+	flag.StringVar(&opts.certPath, "tls-cert-file", defaultCertPath, "Webhook serving certificate")
+	flag.StringVar(&opts.keyPath, "tls-key-file", defaultKeyPath, "Webhook serving key")
+	flag.IntVar(&opts.workers, "workers", 2, "Concurrent node reconcile workers")
+	flag.DurationVar(&opts.shutdownTimeout, "shutdown-timeout", 20*time.Second, "Deadline for draining HTTP connections and in-flight reconciles on shutdown")
+	flag.DurationVar(&opts.shutdownDelay, "shutdown-delay", 5*time.Second, "How long to keep serving after /readyz turns unready on shutdown, so endpoints stop routing admission calls here first")
+	flag.BoolVar(&opts.leaderElect, "leader-elect", false, "Use a Lease so only one replica reconciles nodes (all replicas serve the webhook)")
+	flag.StringVar(&opts.leaderElectNS, "leader-elect-namespace", envOr("POD_NAMESPACE", "kube-system"), "Namespace of the leader election Lease")
+	flag.StringVar(&opts.leaderElectName, "leader-elect-name", "nodetaintshandler", "Name of the leader election Lease")
+	flag.BoolVar(&opts.validateUpdates, "validate-node-updates", false, "Serve /validate-node: deny startup taint removal by non-allowlisted callers while init is pending")
+	flag.StringVar(&opts.controllerUser, "controller-username", "system:serviceaccount:"+envOr("POD_NAMESPACE", "kube-system")+":nodetaintshandler", "Username the controller authenticates as (always allowed to remove the taint)")
+	flag.StringVar(&opts.removalAllow, "taint-removal-allowlist", "", "Comma-separated extra usernames or group:<name> entries allowed to remove the startup taint early")
+	flag.StringVar(&opts.configPath, "config", "", "Readiness policy file (YAML); default policy requires a Ready init pod")
+	flag.IntVar(&opts.initRetries, "init-retries", 3, "Failed init pods tolerated per node before it is marked failed (stays tainted)")
+	flag.StringVar(&opts.initPodTmpl, "init-pod-template", "", "PodTemplate (<namespace>/<name>) the controller launches on each gated node")
+	flag.BoolVar(&opts.componentReport, "component-reports", false, "Serve POST /v1/nodes/{node}/components/{name}/ready for init agents (TokenReview-authenticated)")
+	flag.StringVar(&opts.reportAudiences, "report-audiences", "nodetaintshandler-report", "Comma-separated audiences an init agent's token must carry (projected token audience); empty accepts the apiserver's")
+	flag.StringVar(&opts.reportAccounts, "report-service-accounts", "kube-system/node-startup-init", "Comma-separated <namespace>/<name> service accounts allowed to report readiness; empty allows any pod on the node")
+	flag.StringVar(&opts.requiredConds, "required-node-conditions", "", "Comma-separated Node conditions (Type or Type=Status) required before taint removal, e.g. ImagesPrefetched,GPUDriverReady=True")
+	flag.BoolVar(&opts.initPodCheck, "init-pod-check", true, "Default policy requires a ready init pod; set false to gate only on --required-node-conditions")
+	flag.Float64Var(&opts.releaseRate, "release-rate", 0, "Max taint removals per second (token bucket, oldest node first); 0 = unlimited")
+	flag.IntVar(&opts.releaseBurst, "release-burst", 1, "Taint removals allowed at once before --release-rate applies")
+	flag.Float64Var(&opts.breaker.MaxGatedPercent, "breaker-max-gated-percent", 0, "Trip the startup breaker when more than this % of a pool's nodes are gated longer than --breaker-gated-for; 0 = off")
+	flag.IntVar(&opts.breaker.MaxGatedNodes, "breaker-max-gated-nodes", 0, "Trip the startup breaker when more than this many nodes of a pool are gated longer than --breaker-gated-for; 0 = off")
+	flag.DurationVar(&opts.breaker.GatedFor, "breaker-gated-for", 15*time.Minute, "How long a node must be gated to count against the breaker budget")
+	flag.StringVar(&opts.breaker.PoolLabel, "breaker-pool-label", "", "Node label whose value groups nodes into pools with separate budgets (e.g. karpenter.sh/nodepool); empty = cluster-wide")
+	flag.BoolVar(&opts.breaker.ReleaseOnTrip, "breaker-release", false, "Release every gated node of a pool when its breaker trips")
+	flag.DurationVar(&opts.breaker.Cooldown, "breaker-cooldown", 10*time.Minute, "How long a tripped pool must stay under budget before new nodes are gated again")
+	flag.StringVar(&opts.taintKey, "taint-key", startup.TaintKey, "Startup taint key; use an ignore-taint.cluster-autoscaler.kubernetes.io/ key so Cluster Autoscaler ignores gated nodes")
+	flag.StringVar(&opts.taintValueMatch, "taint-value-match", string(startup.TaintValueAny), "Which values of the startup taint key count as the startup taint: any (key only) or exact")
+	flag.StringVar(&opts.errorPolicy, "webhook-error-policy", string(webhook.ErrorPolicyAllow), "Answer to admission reviews the webhooks cannot process (unreadable, undecodable): allow (unchanged, with a warning) or deny")
+	flag.Int64Var(&opts.webhookLimits.MaxBodyBytes, "webhook-max-body-bytes", webhook.DefaultMaxBodyBytes, "Largest admission request body accepted")
+	flag.DurationVar(&opts.webhookLimits.Timeout, "webhook-request-timeout", webhook.DefaultRequestTimeout, "Deadline for answering one admission request; keep it under the webhook timeoutSeconds")
+	flag.StringVar(&opts.clientCAFile, "client-ca-file", "", "PEM bundle (e.g. the apiserver's client CA) verifying webhook client certificates; certificates from other CAs are refused")
+	flag.BoolVar(&opts.requireClient, "require-client-cert", false, "Reject requests without a certificate verified by --client-ca-file (health endpoints and component reports exempt)")
+	flag.Parse()
+	if opts.webhookAddr == "" {
+		opts.webhookAddr = ":8443"
+		if opts.dev {
+			opts.webhookAddr = "127.0.0.1:8443"
+		}
+	}
+	if opts.healthAddr == "" {
+		opts.healthAddr = ":8080"
+		if opts.dev {
+			opts.healthAddr = "127.0.0.1:8080"
+		}
+	}
+
+	os.Exit(run(opts))
+}
+
+func run(opts options) int {
+	klog.Infof("nodetaintshandler %s (built %s)", version, buildTime)
 	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGINT)
 	defer cancel()
 
-	cfg, err := rest.InClusterConfig()
+	if err := startup.SetTaintKey(opts.taintKey); err != nil {
+		klog.Fatalf("--taint-key: %v", err)
+	}
+	if err := startup.SetTaintValueMatch(startup.TaintValueMatch(opts.taintValueMatch)); err != nil {
+		klog.Fatalf("--taint-value-match: %v", err)
+	}
+	if _, err := webhook.ParseErrorPolicy(opts.errorPolicy); err != nil {
+		klog.Fatalf("--webhook-error-policy: %v", err)
+	}
+	cfg, err := loadRESTConfig(opts.kubeconfig, opts.kubeContext)
 	if err != nil {
-		klog.Fatalf("in-cluster config: %v", err)
+		klog.Fatalf("kube config: %v", err)
 	}
 	clientset, err := kubernetes.NewForConfig(cfg)
 	if err != nil {
 		klog.Fatalf("clientset: %v", err)
 	}
 
-	stop := make(chan struct{})
-	go startup.NewController(clientset).Run(stop)
-
-	// Always start webhook (avoids env misconfig causing 404 probes)
-	startWebhook(ctx)
+	mgr := lifecycle.NewManager(opts.shutdownTimeout)
+	mgr.ShutdownDelay = opts.shutdownDelay
+	// Stopped in reverse order: webhook drains first, then controller workers
+	// finish, then the Lease is released.
+	ctrlOpts := []startup.Option{
+		startup.WithWorkers(opts.workers),
+		startup.WithInitRetries(opts.initRetries),
+		startup.WithReleaseLimit(opts.releaseRate, opts.releaseBurst),
+		startup.WithBreaker(opts.breaker),
+	}
+	readinessOpts, policies, err := startup.ReadinessOptions(opts.configPath, opts.requiredConds, opts.initPodCheck)
+	if err != nil {
+		klog.Fatalf("readiness config: %v", err)
+	}
+	for _, p := range policies {
+		klog.Infof("Readiness policy %q for nodes matching %q", p.Name, p.Selector.String())
+	}
+	ctrlOpts = append(ctrlOpts, readinessOpts...)
+	if opts.initPodTmpl != "" {
+		ns, name, ok := strings.Cut(opts.initPodTmpl, "/")
+		if !ok || ns == "" || name == "" {
+			klog.Fatalf("--init-pod-template must be <namespace>/<name>, got %q", opts.initPodTmpl)
+		}
+		ctrlOpts = append(ctrlOpts, startup.WithInitPodTemplate(types.NamespacedName{Namespace: ns, Name: name}))
+	}
+	var elector *leader.Elector
+	if opts.leaderElect {
+		if elector, err = leader.New(clientset, opts.leaderElectNS, opts.leaderElectName, identity()); err != nil {
+			klog.Fatalf("leader election: %v", err)
+		}
+		ctrlOpts = append(ctrlOpts, startup.WithLeaderGate(elector.Leading()))
+	}
+	ctrl := startup.NewController(clientset, ctrlOpts...)
 
-	go func() {
-		<-ctx.Done()
-		klog.Info("Shutdown signal received")
-		close(stop)
-		time.Sleep(300 * time.Millisecond)
-	}()
+	// Health first: it listens before the serving cert exists, and stops last so
+	// probes see /readyz fail rather than a refused connection while draining.
+	checks := healthChecks{mgr: mgr, ctrl: ctrl, client: clientset, cert: &atomic.Pointer[tls.Certificate]{}, elector: elector}
+	mgr.Add(lifecycle.NewHTTPServer("health", newHealthServer(opts, checks), opts.shutdownTimeout))
+	if elector != nil {
+		mgr.Add(elector)
+	}
+	mgr.Add(lifecycle.ComponentFunc("controller", ctrl.Run))
+	// The webhook waits for its cert on its own; tls-cert keeps /readyz failing until then.
+	mgr.Add(lifecycle.ComponentFunc("webhook", func(ctx context.Context) error {
+		cert, err := servingCert(ctx, opts)
+		if err != nil {
+			if ctx.Err() != nil {
+				// Stopped while waiting for the cert Secret: a clean shutdown.
+				return nil
+			}
+			return fmt.Errorf("load keypair: %w", err)
+		}
+		checks.cert.Store(&cert)
+		srv, err := newWebhookServer(opts, cert, ctrl, clientset)
+		if err != nil {
+			return fmt.Errorf("webhook server: %w", err)
+		}
+		return lifecycle.NewHTTPServer("webhook", srv, opts.shutdownTimeout).Run(ctx)
+	}))
 
 	klog.Info("Controller + webhook running")
-	select {}
-}
-
-func startWebhook(ctx context.Context) {
-	// Wait for mounted certs (handles slight Secret projection delay)
-	if err := waitForFiles(60*time.Second, certPath, keyPath); err != nil {
-		klog.Fatalf("TLS files not available: %v", err)
+	err = mgr.Run(ctx)
+	switch {
+	case errors.Is(err, lifecycle.ErrShutdownTimeout):
+		klog.Errorf("Shutdown incomplete: %v", err)
+		return exitShutdownTimeout
+	case err != nil:
+		klog.Errorf("Exiting: %v", err)
+		return exitComponentFailed
 	}
+	klog.Info("Shutdown complete")
+	return exitOK
+}
 
+// newHealthServer serves the probes and runtime metrics over plain HTTP.
+func newHealthServer(opts options, checks healthChecks) *http.Server {
 	mux := http.NewServeMux()
-	// Business webhook
-	webhook.Register(mux)
-	// Probes
-	mux.HandleFunc("/healthz", func(w http.ResponseWriter, _ *http.Request) {
-		w.WriteHeader(http.StatusOK)
-		_, _ = w.Write([]byte("ok"))
-	})
-	mux.HandleFunc("/readyz", func(w http.ResponseWriter, _ *http.Request) {
-		if !ready.Load() {
-			http.Error(w, "not ready", http.StatusServiceUnavailable)
-			return
-		}
-		w.WriteHeader(http.StatusOK)
-		_, _ = w.Write([]byte("ready"))
-	})
-
-	srv := &http.Server{
-		Addr:              ":8443",
+	healthz.InstallHandler(mux, "/healthz", checks.liveness()...)
+	healthz.InstallHandler(mux, "/readyz", checks.readiness()...)
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		checks.ctrl,
+	)
+	mux.Handle("/metrics", promhttp.HandlerFor(reg, promhttp.HandlerOpts{}))
+	return &http.Server{
+		Addr:              opts.healthAddr,
 		Handler:           mux,
 		ReadHeaderTimeout: 5 * time.Second,
+		ReadTimeout:       10 * time.Second,
+		WriteTimeout:      10 * time.Second,
+		IdleTimeout:       90 * time.Second,
 	}
+}
 
-	// Graceful shutdown
-	go func() {
-		<-ctx.Done()
-		shCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-		defer cancel()
-		_ = srv.Shutdown(shCtx)
-	}()
-
-	cert, err := tls.LoadX509KeyPair(certPath, keyPath)
+func newWebhookServer(opts options, cert tls.Certificate, ctrl *startup.Controller, client kubernetes.Interface) (*http.Server, error) {
+	onError, err := webhook.ParseErrorPolicy(opts.errorPolicy)
 	if err != nil {
-		klog.Fatalf("load keypair: %v", err)
+		return nil, fmt.Errorf("--webhook-error-policy: %w", err)
 	}
-	srv.TLSConfig = &tls.Config{
+	mux := http.NewServeMux()
+	// Business webhook
+	webhook.RegisterMutator(mux, &webhook.NodeMutator{Gate: ctrl, Policies: ctrl, Version: version, OnError: onError, Limits: opts.webhookLimits})
+	if opts.validateUpdates {
+		webhook.RegisterTaintGuard(mux, &webhook.TaintGuard{
+			Status:  ctrl,
+			Allowed: append([]string{opts.controllerUser}, splitList(opts.removalAllow)...),
+			OnError: onError,
+			Limits:  opts.webhookLimits,
+		})
+	}
+	if opts.componentReport {
+		report.Register(mux, &report.Reporter{
+			Client:          client,
+			Audiences:       splitList(opts.reportAudiences),
+			ServiceAccounts: splitList(opts.reportAccounts),
+		})
+	}
+
+	tlsConfig := &tls.Config{
 		MinVersion:   tls.VersionTLS12,
 		Certificates: []tls.Certificate{cert},
 	}
-
-	go func() {
-		klog.Infof("Starting webhook HTTPS server on %s", srv.Addr)
-		ready.Store(true) // mark ready just before serving
-		if err := srv.ListenAndServeTLS("", ""); err != nil && !errors.Is(err, http.ErrServerClosed) {
-			klog.Fatalf("webhook server error: %v", err)
+	var handler http.Handler = mux
+	if opts.clientCAFile != "" {
+		pool, err := certs.LoadCAPool(opts.clientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("--client-ca-file: %w", err)
+		}
+		// Probes come without a certificate; RequireClientCert enforces per path.
+		tlsConfig.ClientCAs = pool
+		tlsConfig.ClientAuth = tls.VerifyClientCertIfGiven
+	}
+	if opts.requireClient {
+		if opts.clientCAFile == "" {
+			return nil, errors.New("--require-client-cert needs --client-ca-file")
+		}
+		var exempt []string
+		if opts.componentReport {
+			// Init agents authenticate with ServiceAccount tokens (TokenReview).
+			exempt = append(exempt, "/v1/")
 		}
-	}()
+		handler = certs.RequireClientCert(mux, exempt...)
+		klog.Infof("Client certificates required (exempt: %v)", exempt)
+	}
+
+	return &http.Server{
+		Addr:              opts.webhookAddr,
+		Handler:           handler,
+		ReadHeaderTimeout: 5 * time.Second,
+		ReadTimeout:       10 * time.Second,
+		// Room for the admission deadline plus writing its answer.
+		WriteTimeout: opts.webhookLimits.Timeout + 10*time.Second,
+		IdleTimeout:  90 * time.Second,
+		TLSConfig:    tlsConfig,
+	}, nil
+}
+
+// servingCert returns the webhook certificate: self-signed for localhost in dev
+// mode, otherwise the mounted key pair.
+func servingCert(ctx context.Context, opts options) (tls.Certificate, error) {
+	if opts.dev {
+		klog.Warning("Dev mode: serving webhook with a self-signed certificate for localhost")
+		return certs.SelfSigned(24*time.Hour, "localhost", "127.0.0.1")
+	}
+	// Wait for mounted certs (handles slight Secret projection delay)
+	if err := waitForFiles(ctx, 60*time.Second, opts.certPath, opts.keyPath); err != nil {
+		return tls.Certificate{}, err
+	}
+	return tls.LoadX509KeyPair(opts.certPath, opts.keyPath)
 }
 
-func waitForFiles(timeout time.Duration, paths ...string) error {
+func waitForFiles(ctx context.Context, timeout time.Duration, paths ...string) error {
 	deadline := time.Now().Add(timeout)
 	for {
 		missing := false
@@ -127,8 +355,41 @@ func waitForFiles(timeout time.Duration, paths ...string) error {
 		if time.Now().After(deadline) {
 			return errors.New("timeout waiting for TLS files")
 		}
-		time.Sleep(300 * time.Millisecond)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(300 * time.Millisecond):
+		}
+	}
+}
+
+func splitList(s string) []string {
+	var out []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+func envOr(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
+
+// identity names this replica for leader election (pod name via hostname).
+func identity() string {
+	if v := os.Getenv("POD_NAME"); v != "" {
+		return v
+	}
+	h, err := os.Hostname()
+	if err != nil {
+		return "nodetaintshandler"
 	}
+	return h
 }
 
 func fileExists(p string) bool {
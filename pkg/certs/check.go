@@ -0,0 +1,31 @@
+package certs
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// CheckValid reports an error when the serving certificate is missing, not yet
+// valid or expired at now.
+func CheckValid(cert *tls.Certificate, now time.Time) error {
+	if cert == nil || len(cert.Certificate) == 0 {
+		return errors.New("no certificate loaded")
+	}
+	leaf := cert.Leaf
+	if leaf == nil {
+		var err error
+		if leaf, err = x509.ParseCertificate(cert.Certificate[0]); err != nil {
+			return fmt.Errorf("parse certificate: %w", err)
+		}
+	}
+	if now.Before(leaf.NotBefore) {
+		return fmt.Errorf("certificate not valid before %s", leaf.NotBefore.UTC().Format(time.RFC3339))
+	}
+	if now.After(leaf.NotAfter) {
+		return fmt.Errorf("certificate expired at %s", leaf.NotAfter.UTC().Format(time.RFC3339))
+	}
+	return nil
+}
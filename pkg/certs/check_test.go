@@ -0,0 +1,30 @@
+package certs
+
+import (
+	"crypto/tls"
+	"testing"
+	"time"
+)
+
+func TestCheckValid(t *testing.T) {
+	cert, err := SelfSigned(time.Hour, "localhost")
+	if err != nil {
+		t.Fatalf("generate: %v", err)
+	}
+	if err := CheckValid(&cert, time.Now()); err != nil {
+		t.Fatalf("fresh cert: %v", err)
+	}
+	if err := CheckValid(&cert, time.Now().Add(2*time.Hour)); err == nil {
+		t.Fatalf("expected expiry error")
+	}
+	if err := CheckValid(&cert, time.Now().Add(-time.Hour)); err == nil {
+		t.Fatalf("expected not-yet-valid error")
+	}
+	cert.Leaf = nil // parsed lazily from DER
+	if err := CheckValid(&cert, time.Now()); err != nil {
+		t.Fatalf("unparsed leaf: %v", err)
+	}
+	if err := CheckValid(&tls.Certificate{}, time.Now()); err == nil {
+		t.Fatalf("expected error for empty certificate")
+	}
+}
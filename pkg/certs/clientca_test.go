@@ -0,0 +1,66 @@
+package certs
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLoadCAPool(t *testing.T) {
+	ca, err := SelfSigned(time.Hour, "client-ca")
+	if err != nil {
+		t.Fatalf("generate: %v", err)
+	}
+	dir := t.TempDir()
+	good := filepath.Join(dir, "ca.crt")
+	os.WriteFile(good, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.Certificate[0]}), 0o600)
+	bad := filepath.Join(dir, "bad.crt")
+	os.WriteFile(bad, []byte("not pem"), 0o600)
+
+	pool, err := LoadCAPool(good)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if _, err := ca.Leaf.Verify(x509.VerifyOptions{Roots: pool}); err != nil {
+		t.Fatalf("verify against loaded pool: %v", err)
+	}
+	if _, err := LoadCAPool(bad); err == nil {
+		t.Fatalf("expected error for a file without certificates")
+	}
+	if _, err := LoadCAPool(filepath.Join(dir, "missing.crt")); err == nil {
+		t.Fatalf("expected error for a missing file")
+	}
+}
+
+func TestRequireClientCert(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) { w.WriteHeader(http.StatusOK) })
+	h := RequireClientCert(ok, "/healthz", "/readyz")
+	verified := &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{{}}}}
+	cases := []struct {
+		path  string
+		state *tls.ConnectionState
+		want  int
+	}{
+		{"/mutate-node", nil, http.StatusUnauthorized},
+		{"/mutate-node", &tls.ConnectionState{}, http.StatusUnauthorized},
+		{"/mutate-node", verified, http.StatusOK},
+		{"/healthz", &tls.ConnectionState{}, http.StatusOK},
+		{"/readyz/informer-sync", &tls.ConnectionState{}, http.StatusOK},
+		{"/readyzz", &tls.ConnectionState{}, http.StatusUnauthorized},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest(http.MethodPost, tc.path, nil)
+		req.TLS = tc.state
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, req)
+		if rr.Code != tc.want {
+			t.Errorf("%s (verified=%v): status %d want %d", tc.path, tc.state == verified, rr.Code, tc.want)
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package certs
+
+import (
+	"crypto/x509"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestSelfSigned_HostsAndValidity(t *testing.T) {
+	cert, err := SelfSigned(time.Hour, "localhost", "127.0.0.1")
+	if err != nil {
+		t.Fatalf("generate: %v", err)
+	}
+	leaf := cert.Leaf
+	if leaf == nil {
+		t.Fatalf("expected parsed leaf")
+	}
+	if len(leaf.DNSNames) != 1 || leaf.DNSNames[0] != "localhost" {
+		t.Fatalf("unexpected DNS names %v", leaf.DNSNames)
+	}
+	if len(leaf.IPAddresses) != 1 || !leaf.IPAddresses[0].Equal(net.ParseIP("127.0.0.1")) {
+		t.Fatalf("unexpected IPs %v", leaf.IPAddresses)
+	}
+	if leaf.NotAfter.Before(time.Now().Add(59 * time.Minute)) {
+		t.Fatalf("cert expires too early: %v", leaf.NotAfter)
+	}
+	pool := x509.NewCertPool()
+	pool.AddCert(leaf)
+	if _, err := leaf.Verify(x509.VerifyOptions{DNSName: "localhost", Roots: pool}); err != nil {
+		t.Fatalf("verify against itself: %v", err)
+	}
+}
+
+func TestSelfSigned_NoHosts(t *testing.T) {
+	if _, err := SelfSigned(time.Hour); err == nil {
+		t.Fatalf("expected error without hosts")
+	}
+}
@@ -0,0 +1,109 @@
+package healthz
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"k8s.io/klog/v2"
+)
+
+// Checker is a named sub-check (same shape as the apiserver's healthz.HealthChecker,
+// so client-go adaptors like the leader election one plug in directly).
+type Checker interface {
+	Name() string
+	Check(req *http.Request) error
+}
+
+// StatusReporter can be implemented by a Checker to add detail to verbose output,
+// e.g. "[+]leader-election ok (leading)".
+type StatusReporter interface {
+	Status() string
+}
+
+type namedCheck struct {
+	name  string
+	check func(*http.Request) error
+}
+
+func (n namedCheck) Name() string                  { return n.name }
+func (n namedCheck) Check(req *http.Request) error { return n.check(req) }
+
+// NamedCheck wraps a function as a Checker.
+func NamedCheck(name string, check func(*http.Request) error) Checker {
+	return namedCheck{name: name, check: check}
+}
+
+// Ping always succeeds; proves the server is serving.
+var Ping = NamedCheck("ping", func(*http.Request) error { return nil })
+
+// InstallHandler serves the composite check at path and each sub-check at path/<name>.
+// Like kube-apiserver: "?verbose" lists every check, "?exclude=<name>" skips one.
+func InstallHandler(mux *http.ServeMux, path string, checks ...Checker) {
+	mux.Handle(path, handleRootHealth(strings.TrimPrefix(path, "/"), checks))
+	for _, c := range checks {
+		mux.Handle(path+"/"+c.Name(), handleSingle(c))
+	}
+}
+
+func handleRootHealth(name string, checks []Checker) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		excluded := map[string]bool{}
+		for _, e := range r.URL.Query()["exclude"] {
+			for _, n := range strings.Split(e, ",") {
+				excluded[strings.TrimSpace(n)] = true
+			}
+		}
+		var out bytes.Buffer
+		var failed []string
+		for _, c := range checks {
+			if excluded[c.Name()] {
+				fmt.Fprintf(&out, "[+]%s excluded: ok\n", c.Name())
+				continue
+			}
+			if err := c.Check(r); err != nil {
+				fmt.Fprintf(&out, "[-]%s failed: %v\n", c.Name(), err)
+				failed = append(failed, c.Name())
+				continue
+			}
+			fmt.Fprintf(&out, "[+]%s ok%s\n", c.Name(), status(c))
+		}
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		if len(failed) > 0 {
+			klog.V(2).Infof("%s check failed: %s", name, strings.Join(failed, ","))
+			w.WriteHeader(http.StatusInternalServerError)
+			fmt.Fprintf(&out, "%s check failed\n", name)
+			_, _ = out.WriteTo(w)
+			return
+		}
+		if _, verbose := r.URL.Query()["verbose"]; !verbose {
+			_, _ = w.Write([]byte("ok"))
+			return
+		}
+		fmt.Fprintf(&out, "%s check passed\n", name)
+		_, _ = out.WriteTo(w)
+	}
+}
+
+func handleSingle(c Checker) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		if err := c.Check(r); err != nil {
+			http.Error(w, fmt.Sprintf("internal server error: %v", err), http.StatusInternalServerError)
+			return
+		}
+		_, _ = w.Write([]byte("ok" + status(c)))
+	}
+}
+
+func status(c Checker) string {
+	if sr, ok := c.(StatusReporter); ok {
+		if s := sr.Status(); s != "" {
+			return " (" + s + ")"
+		}
+	}
+	return ""
+}
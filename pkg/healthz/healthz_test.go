@@ -0,0 +1,66 @@
+package healthz
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type reporting struct{ Checker }
+
+func (reporting) Status() string { return "leading" }
+
+func serve(t *testing.T, target string, checks ...Checker) *httptest.ResponseRecorder {
+	t.Helper()
+	mux := http.NewServeMux()
+	InstallHandler(mux, "/readyz", checks...)
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, target, nil))
+	return rr
+}
+
+var failing = NamedCheck("informer-sync", func(*http.Request) error { return errors.New("caches not synced") })
+
+func TestInstallHandler_AllPass(t *testing.T) {
+	rr := serve(t, "/readyz", Ping)
+	if rr.Code != http.StatusOK || rr.Body.String() != "ok" {
+		t.Fatalf("got %d %q", rr.Code, rr.Body.String())
+	}
+}
+
+func TestInstallHandler_Verbose(t *testing.T) {
+	rr := serve(t, "/readyz?verbose", Ping, reporting{NamedCheck("leader-election", func(*http.Request) error { return nil })})
+	want := "[+]ping ok\n[+]leader-election ok (leading)\nreadyz check passed\n"
+	if rr.Code != http.StatusOK || rr.Body.String() != want {
+		t.Fatalf("got %d %q", rr.Code, rr.Body.String())
+	}
+}
+
+func TestInstallHandler_FailureListsChecks(t *testing.T) {
+	rr := serve(t, "/readyz", Ping, failing)
+	if rr.Code != http.StatusInternalServerError {
+		t.Fatalf("expected 500, got %d", rr.Code)
+	}
+	body := rr.Body.String()
+	if !strings.Contains(body, "[-]informer-sync failed: caches not synced") || !strings.HasSuffix(body, "readyz check failed\n") {
+		t.Fatalf("unexpected body %q", body)
+	}
+}
+
+func TestInstallHandler_Exclude(t *testing.T) {
+	rr := serve(t, "/readyz?exclude=informer-sync", Ping, failing)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected excluded check to be skipped, got %d %q", rr.Code, rr.Body.String())
+	}
+}
+
+func TestInstallHandler_SingleCheckPath(t *testing.T) {
+	if rr := serve(t, "/readyz/ping", Ping, failing); rr.Code != http.StatusOK {
+		t.Fatalf("ping sub-path: %d", rr.Code)
+	}
+	if rr := serve(t, "/readyz/informer-sync", Ping, failing); rr.Code != http.StatusInternalServerError {
+		t.Fatalf("failing sub-path: %d", rr.Code)
+	}
+}
@@ -0,0 +1,140 @@
+// Package jsonpatch builds RFC 6902 JSON Patches by diffing two documents, so
+// admission handlers can mutate a copy of an object and send only the difference.
+package jsonpatch
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Operation is one RFC 6902 operation. Diff emits add, remove and replace.
+type Operation struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path"`
+	Value interface{} `json:"value,omitempty"`
+}
+
+// MarshalJSON always includes the value of add and replace, even when it is null.
+func (o Operation) MarshalJSON() ([]byte, error) {
+	if o.Op == "add" || o.Op == "replace" {
+		return json.Marshal(struct {
+			Op    string      `json:"op"`
+			Path  string      `json:"path"`
+			Value interface{} `json:"value"`
+		}{o.Op, o.Path, o.Value})
+	}
+	return json.Marshal(struct {
+		Op   string `json:"op"`
+		Path string `json:"path"`
+	}{o.Op, o.Path})
+}
+
+// EscapePointer escapes a map key for use as an RFC 6901 path segment (e.g.
+// "startup.k8s.io/ready" -> "startup.k8s.io~1ready").
+func EscapePointer(s string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(s)
+}
+
+// Diff returns operations that turn original into modified. Both are compared in
+// their JSON form; object keys are visited in sorted order so output is stable.
+func Diff(original, modified interface{}) ([]Operation, error) {
+	a, err := normalize(original)
+	if err != nil {
+		return nil, fmt.Errorf("original: %w", err)
+	}
+	b, err := normalize(modified)
+	if err != nil {
+		return nil, fmt.Errorf("modified: %w", err)
+	}
+	return diff("", a, b, nil), nil
+}
+
+func normalize(v interface{}) (interface{}, error) {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return decode(raw)
+}
+
+func decode(raw []byte) (interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	var out interface{}
+	if err := dec.Decode(&out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func diff(path string, a, b interface{}, ops []Operation) []Operation {
+	switch av := a.(type) {
+	case map[string]interface{}:
+		if bv, ok := b.(map[string]interface{}); ok {
+			return diffObject(path, av, bv, ops)
+		}
+	case []interface{}:
+		if bv, ok := b.([]interface{}); ok {
+			return diffArray(path, av, bv, ops)
+		}
+	}
+	if reflect.DeepEqual(a, b) {
+		return ops
+	}
+	return append(ops, Operation{Op: "replace", Path: path, Value: b})
+}
+
+func diffObject(path string, a, b map[string]interface{}, ops []Operation) []Operation {
+	for _, k := range sortedKeys(a) {
+		p := path + "/" + EscapePointer(k)
+		if bv, ok := b[k]; ok {
+			ops = diff(p, a[k], bv, ops)
+		} else {
+			ops = append(ops, Operation{Op: "remove", Path: p})
+		}
+	}
+	for _, k := range sortedKeys(b) {
+		if _, ok := a[k]; !ok {
+			ops = append(ops, Operation{Op: "add", Path: path + "/" + EscapePointer(k), Value: b[k]})
+		}
+	}
+	return ops
+}
+
+// diffArray patches the common prefix element by element (replacing an element
+// outright when that is shorter), then appends or trims the tail.
+func diffArray(path string, a, b []interface{}, ops []Operation) []Operation {
+	n := len(a)
+	if len(b) < n {
+		n = len(b)
+	}
+	for i := 0; i < n; i++ {
+		p := path + "/" + strconv.Itoa(i)
+		elem := diff(p, a[i], b[i], nil)
+		if len(elem) > 1 {
+			elem = []Operation{{Op: "replace", Path: p, Value: b[i]}}
+		}
+		ops = append(ops, elem...)
+	}
+	for i := len(a) - 1; i >= n; i-- {
+		ops = append(ops, Operation{Op: "remove", Path: path + "/" + strconv.Itoa(i)})
+	}
+	for i := n; i < len(b); i++ {
+		ops = append(ops, Operation{Op: "add", Path: path + "/-", Value: b[i]})
+	}
+	return ops
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,112 @@
+package jsonpatch
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	evanphx "gopkg.in/evanphx/json-patch.v4"
+)
+
+func mustDecode(t testing.TB, s string) interface{} {
+	t.Helper()
+	v, err := decode([]byte(s))
+	if err != nil {
+		t.Fatalf("decode %s: %v", s, err)
+	}
+	return v
+}
+
+// roundTrip diffs a and b, applies the patch to a with an independent RFC 6902
+// implementation (the one the apiserver uses) and checks the result equals b.
+func roundTrip(t testing.TB, a, b string) []Operation {
+	t.Helper()
+	ops, err := Diff(mustDecode(t, a), mustDecode(t, b))
+	if err != nil {
+		t.Fatalf("diff: %v", err)
+	}
+	patch, err := json.Marshal(ops)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	decoded, err := evanphx.DecodePatch(patch)
+	if err != nil {
+		t.Fatalf("decode patch %s: %v", patch, err)
+	}
+	out, err := decoded.Apply([]byte(a))
+	if err != nil {
+		t.Fatalf("apply %s to %s: %v", patch, a, err)
+	}
+	if got, want := mustDecode(t, string(out)), mustDecode(t, b); !reflect.DeepEqual(got, want) {
+		t.Fatalf("patch %s turned %s into %s, want %s", patch, a, out, b)
+	}
+	return ops
+}
+
+func TestDiff(t *testing.T) {
+	cases := []struct {
+		name, a, b string
+		want       []Operation
+	}{
+		{"equal", `{"a":1}`, `{"a":1}`, nil},
+		{"add member", `{"spec":{}}`, `{"spec":{"taints":[{"key":"k"}]}}`,
+			[]Operation{{Op: "add", Path: "/spec/taints", Value: []interface{}{map[string]interface{}{"key": "k"}}}}},
+		{"escaped keys", `{"m":{"a/b":"1","c~d":"2"}}`, `{"m":{"c~d":"3"}}`,
+			[]Operation{{Op: "remove", Path: "/m/a~1b"}, {Op: "replace", Path: "/m/c~0d", Value: "3"}}},
+		{"append", `{"t":[1]}`, `{"t":[1,2]}`, []Operation{{Op: "add", Path: "/t/-", Value: json.Number("2")}}},
+		{"trim", `{"t":[1,2,3]}`, `{"t":[1]}`, []Operation{{Op: "remove", Path: "/t/2"}, {Op: "remove", Path: "/t/1"}}},
+		{"element field", `{"t":[{"k":"a","v":"x"}]}`, `{"t":[{"k":"a","v":"y"}]}`,
+			[]Operation{{Op: "replace", Path: "/t/0/v", Value: "y"}}},
+		{"element replaced", `{"t":[{"k":"a","v":"x"}]}`, `{"t":[{"k":"b","v":"y"}]}`,
+			[]Operation{{Op: "replace", Path: "/t/0", Value: map[string]interface{}{"k": "b", "v": "y"}}}},
+		{"null value", `{}`, `{"a":null}`, []Operation{{Op: "add", Path: "/a", Value: nil}}},
+		{"root type", `[1]`, `{"a":1}`, []Operation{{Op: "replace", Path: "", Value: map[string]interface{}{"a": json.Number("1")}}}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := roundTrip(t, tc.a, tc.b); !reflect.DeepEqual(got, tc.want) {
+				t.Fatalf("got %+v, want %+v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestOperation_MarshalNullValue(t *testing.T) {
+	out, _ := json.Marshal([]Operation{{Op: "add", Path: "/a"}, {Op: "remove", Path: "/b"}})
+	if string(out) != `[{"op":"add","path":"/a","value":null},{"op":"remove","path":"/b"}]` {
+		t.Fatalf("unexpected %s", out)
+	}
+}
+
+func FuzzDiff(f *testing.F) {
+	seeds := [][2]string{
+		{`{}`, `{"a":1}`},
+		{`{"metadata":{"annotations":{"startup.k8s.io/completedAt":"1"}},"spec":{}}`, `{"metadata":{},"spec":{"taints":[{"key":"startup.k8s.io/initializing"}]}}`},
+		{`{"t":[{"k":"a"},{"k":"b"},{"k":"c"}]}`, `{"t":[{"k":"c"},{"k":"b"}]}`},
+		{`{"~":{"/":[null,true]}}`, `{"~":{"/":[false]},"":""}`},
+		{`[1,[2,3]]`, `[1,[3],4]`},
+		{`["x"]`, `{"x":null}`},
+	}
+	for _, s := range seeds {
+		f.Add(s[0], s[1])
+	}
+	f.Fuzz(func(t *testing.T, a, b string) {
+		if !json.Valid([]byte(a)) || !json.Valid([]byte(b)) {
+			return
+		}
+		// Admission patches turn objects into objects; json-patch does not
+		// handle scalar documents (or a scalar replacing the root).
+		if !isContainer(mustDecode(t, a)) || !isContainer(mustDecode(t, b)) {
+			return
+		}
+		roundTrip(t, a, b)
+	})
+}
+
+func isContainer(v interface{}) bool {
+	switch v.(type) {
+	case map[string]interface{}, []interface{}:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,123 @@
+package leader
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+	"k8s.io/klog/v2"
+)
+
+// ErrLostLeadership is returned by Run when the lease is lost; the process should exit.
+var ErrLostLeadership = errors.New("leader election lost")
+
+const (
+	leaseDuration = 15 * time.Second
+	renewDeadline = 10 * time.Second
+	retryPeriod   = 2 * time.Second
+)
+
+// Elector runs Lease-based leader election. It is a lifecycle component, a health
+// checker (fails when the held lease could not be renewed) and reports its status.
+type Elector struct {
+	identity string
+	lock     resourcelock.Interface
+	leading  chan struct{}
+
+	mu sync.Mutex
+	le *leaderelection.LeaderElector
+}
+
+func New(client kubernetes.Interface, namespace, name, identity string) (*Elector, error) {
+	lock, err := resourcelock.New(
+		resourcelock.LeasesResourceLock,
+		namespace, name,
+		client.CoreV1(), client.CoordinationV1(),
+		resourcelock.ResourceLockConfig{Identity: identity},
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &Elector{identity: identity, lock: lock, leading: make(chan struct{})}, nil
+}
+
+func (e *Elector) Name() string { return "leader-election" }
+
+// Leading is closed once this process acquires the lease.
+func (e *Elector) Leading() <-chan struct{} { return e.leading }
+
+// Run campaigns for the lease until ctx is cancelled (lease released) or leadership is lost.
+func (e *Elector) Run(ctx context.Context) error {
+	lost := false
+	le, err := leaderelection.NewLeaderElector(leaderelection.LeaderElectionConfig{
+		Lock:            e.lock,
+		LeaseDuration:   leaseDuration,
+		RenewDeadline:   renewDeadline,
+		RetryPeriod:     retryPeriod,
+		ReleaseOnCancel: true,
+		Name:            e.lock.Describe(),
+		Callbacks: leaderelection.LeaderCallbacks{
+			OnStartedLeading: func(context.Context) {
+				klog.Infof("Acquired leadership as %s", e.identity)
+				close(e.leading)
+			},
+			OnStoppedLeading: func() {
+				if ctx.Err() == nil {
+					lost = true
+				}
+			},
+			OnNewLeader: func(id string) {
+				if id != e.identity {
+					klog.Infof("Current leader: %s", id)
+				}
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	e.mu.Lock()
+	e.le = le
+	e.mu.Unlock()
+
+	le.Run(ctx) // returns when ctx is cancelled or leadership is lost
+	if lost {
+		return ErrLostLeadership
+	}
+	return nil
+}
+
+func (e *Elector) elector() *leaderelection.LeaderElector {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.le
+}
+
+// Check fails when we hold the lease but have not renewed it in time.
+func (e *Elector) Check(_ *http.Request) error {
+	le := e.elector()
+	if le == nil {
+		return nil
+	}
+	return le.Check(renewDeadline)
+}
+
+// Status reports "leading", "following <id>" or "campaigning" for verbose health output.
+func (e *Elector) Status() string {
+	le := e.elector()
+	switch {
+	case le == nil:
+		return "campaigning"
+	case le.IsLeader():
+		return "leading"
+	case le.GetLeader() != "":
+		return "following " + le.GetLeader()
+	default:
+		return "campaigning"
+	}
+}
@@ -0,0 +1,54 @@
+package leader
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestElector_AcquiresAndReleasesLease(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	e, err := New(client, "kube-system", "nodetaintshandler", "pod-a")
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+	if got := e.Status(); got != "campaigning" {
+		t.Fatalf("status before run = %q", got)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- e.Run(ctx) }()
+
+	select {
+	case <-e.Leading():
+	case <-time.After(5 * time.Second):
+		t.Fatalf("did not acquire lease")
+	}
+	if got := e.Status(); got != "leading" {
+		t.Fatalf("status = %q, want leading", got)
+	}
+	if err := e.Check(nil); err != nil {
+		t.Fatalf("healthy leader check failed: %v", err)
+	}
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("run: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Run did not return after cancel")
+	}
+	lease, err := client.CoordinationV1().Leases("kube-system").Get(context.TODO(), "nodetaintshandler", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("get lease: %v", err)
+	}
+	if lease.Spec.HolderIdentity != nil && *lease.Spec.HolderIdentity == "pod-a" {
+		t.Fatalf("lease not released on cancel")
+	}
+}
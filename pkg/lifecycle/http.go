@@ -0,0 +1,51 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"k8s.io/klog/v2"
+)
+
+// HTTPServer runs an *http.Server as a Component. TLS is used when srv.TLSConfig is set.
+// On stop, in-flight requests are drained for up to drainTimeout.
+type HTTPServer struct {
+	name         string
+	srv          *http.Server
+	drainTimeout time.Duration
+}
+
+func NewHTTPServer(name string, srv *http.Server, drainTimeout time.Duration) *HTTPServer {
+	return &HTTPServer{name: name, srv: srv, drainTimeout: drainTimeout}
+}
+
+func (h *HTTPServer) Name() string { return h.name }
+
+func (h *HTTPServer) Run(ctx context.Context) error {
+	errCh := make(chan error, 1)
+	go func() {
+		var err error
+		if h.srv.TLSConfig != nil {
+			klog.Infof("Starting %s HTTPS server on %s", h.name, h.srv.Addr)
+			err = h.srv.ListenAndServeTLS("", "")
+		} else {
+			klog.Infof("Starting %s HTTP server on %s", h.name, h.srv.Addr)
+			err = h.srv.ListenAndServe()
+		}
+		errCh <- err
+	}()
+
+	select {
+	case err := <-errCh:
+		if errors.Is(err, http.ErrServerClosed) {
+			return nil
+		}
+		return err
+	case <-ctx.Done():
+	}
+	shCtx, cancel := context.WithTimeout(context.Background(), h.drainTimeout)
+	defer cancel()
+	return h.srv.Shutdown(shCtx)
+}
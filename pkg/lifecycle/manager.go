@@ -0,0 +1,131 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"k8s.io/klog/v2"
+)
+
+// ErrShutdownTimeout is returned by Manager.Run when components did not stop within the deadline.
+var ErrShutdownTimeout = errors.New("shutdown deadline exceeded")
+
+// Component is a long-running part of the process.
+// Run blocks until ctx is cancelled (then drains and returns) or the component fails.
+type Component interface {
+	Name() string
+	Run(ctx context.Context) error
+}
+
+type funcComponent struct {
+	name string
+	run  func(ctx context.Context) error
+}
+
+func (f funcComponent) Name() string                  { return f.name }
+func (f funcComponent) Run(ctx context.Context) error { return f.run(ctx) }
+
+// ComponentFunc adapts a run function into a Component.
+func ComponentFunc(name string, run func(ctx context.Context) error) Component {
+	return funcComponent{name: name, run: run}
+}
+
+// Manager runs components and shuts them down in order:
+// readiness flips to false first, then, after ShutdownDelay, components stop in
+// reverse registration order, each allowed to drain until the shared
+// ShutdownTimeout expires.
+type Manager struct {
+	ShutdownTimeout time.Duration
+	// ShutdownDelay keeps components serving after readiness flips, so endpoints
+	// drop the pod before its listeners close.
+	ShutdownDelay time.Duration
+
+	components []Component
+	ready      atomic.Bool
+}
+
+func NewManager(shutdownTimeout time.Duration) *Manager {
+	return &Manager{ShutdownTimeout: shutdownTimeout}
+}
+
+// Add registers a component. Components start in registration order and stop in reverse.
+func (m *Manager) Add(c Component) {
+	m.components = append(m.components, c)
+}
+
+// Ready reports whether all components were started and shutdown has not begun.
+func (m *Manager) Ready() bool {
+	return m.ready.Load()
+}
+
+type running struct {
+	c      Component
+	cancel context.CancelFunc
+	done   chan struct{}
+	err    error
+}
+
+// Run starts all components and blocks until ctx is cancelled or a component exits.
+// It returns nil on a clean shutdown, the first component failure otherwise, joined
+// with ErrShutdownTimeout when draining exceeded the deadline.
+func (m *Manager) Run(ctx context.Context) error {
+	exited := make(chan *running, len(m.components))
+	runs := make([]*running, 0, len(m.components))
+	for _, c := range m.components {
+		cctx, cancel := context.WithCancel(context.Background())
+		r := &running{c: c, cancel: cancel, done: make(chan struct{})}
+		runs = append(runs, r)
+		go func() {
+			r.err = r.c.Run(cctx)
+			close(r.done)
+			exited <- r
+		}()
+		klog.Infof("Started component %s", c.Name())
+	}
+	m.ready.Store(true)
+
+	var failure error
+	select {
+	case <-ctx.Done():
+		klog.Info("Shutdown signal received")
+	case r := <-exited:
+		failure = r.err
+		if failure == nil {
+			failure = errors.New("exited unexpectedly")
+		}
+		failure = fmt.Errorf("component %s: %w", r.c.Name(), failure)
+		klog.Errorf("%v; shutting down", failure)
+	}
+
+	// Stop advertising readiness before draining so traffic moves elsewhere.
+	m.ready.Store(false)
+	if m.ShutdownDelay > 0 {
+		klog.Infof("Unready; stopping components in %s", m.ShutdownDelay)
+		time.Sleep(m.ShutdownDelay)
+	}
+
+	deadline := time.NewTimer(m.ShutdownTimeout)
+	defer deadline.Stop()
+	for i := len(runs) - 1; i >= 0; i-- {
+		r := runs[i]
+		r.cancel()
+		select {
+		case <-r.done:
+			if r.err != nil && failure == nil {
+				failure = fmt.Errorf("component %s: %w", r.c.Name(), r.err)
+			}
+			klog.Infof("Stopped component %s", r.c.Name())
+		case <-deadline.C:
+			// Cancel the rest so they at least begin stopping before we exit.
+			for j := i - 1; j >= 0; j-- {
+				runs[j].cancel()
+			}
+			klog.Errorf("Component %s did not stop within %s", r.c.Name(), m.ShutdownTimeout)
+			return errors.Join(failure, ErrShutdownTimeout)
+		}
+	}
+	return failure
+}
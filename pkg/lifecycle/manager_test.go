@@ -0,0 +1,181 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recorder collects component stop order.
+type recorder struct {
+	mu    sync.Mutex
+	order []string
+}
+
+func (r *recorder) add(s string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.order = append(r.order, s)
+}
+
+func (r *recorder) get() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.order...)
+}
+
+func blocking(name string, rec *recorder, m *Manager, readyAtStop *bool) Component {
+	return ComponentFunc(name, func(ctx context.Context) error {
+		<-ctx.Done()
+		if readyAtStop != nil {
+			*readyAtStop = m.Ready()
+		}
+		rec.add(name)
+		return nil
+	})
+}
+
+func TestManager_StopsInReverseOrderAfterUnready(t *testing.T) {
+	rec := &recorder{}
+	m := NewManager(time.Second)
+	readyAtStop := true
+	m.Add(blocking("a", rec, m, nil))
+	m.Add(blocking("b", rec, m, &readyAtStop))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- m.Run(ctx) }()
+	waitFor(t, m.Ready)
+	cancel()
+
+	if err := <-done; err != nil {
+		t.Fatalf("expected clean shutdown, got %v", err)
+	}
+	got := rec.get()
+	if len(got) != 2 || got[0] != "b" || got[1] != "a" {
+		t.Fatalf("unexpected stop order %v", got)
+	}
+	if readyAtStop {
+		t.Fatalf("readiness should flip before components stop")
+	}
+}
+
+func TestManager_ComponentFailureStopsOthers(t *testing.T) {
+	rec := &recorder{}
+	m := NewManager(time.Second)
+	m.Add(blocking("a", rec, m, nil))
+	m.Add(ComponentFunc("bad", func(context.Context) error { return errors.New("boom") }))
+
+	err := m.Run(context.Background())
+	if err == nil || errors.Is(err, ErrShutdownTimeout) {
+		t.Fatalf("expected component failure, got %v", err)
+	}
+	if got := rec.get(); len(got) != 1 || got[0] != "a" {
+		t.Fatalf("expected healthy component stopped, got %v", got)
+	}
+}
+
+func TestManager_ShutdownTimeout(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	m := NewManager(50 * time.Millisecond)
+	m.Add(ComponentFunc("stuck", func(context.Context) error {
+		<-release
+		return nil
+	}))
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := m.Run(ctx); !errors.Is(err, ErrShutdownTimeout) {
+		t.Fatalf("expected ErrShutdownTimeout, got %v", err)
+	}
+}
+
+func TestHTTPServer_DrainsInFlightRequest(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+
+	started := make(chan struct{})
+	mux := http.NewServeMux()
+	mux.HandleFunc("/slow", func(w http.ResponseWriter, _ *http.Request) {
+		close(started)
+		time.Sleep(100 * time.Millisecond)
+		_, _ = w.Write([]byte("done"))
+	})
+	h := NewHTTPServer("test", &http.Server{Addr: addr, Handler: mux}, time.Second)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	runErr := make(chan error, 1)
+	go func() { runErr <- h.Run(ctx) }()
+
+	respErr := make(chan error, 1)
+	go func() {
+		for i := 0; i < 50; i++ {
+			resp, err := http.Get("http://" + addr + "/slow")
+			if err == nil {
+				resp.Body.Close()
+				if resp.StatusCode != http.StatusOK {
+					err = errors.New(resp.Status)
+				}
+				respErr <- err
+				return
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		respErr <- errors.New("server never came up")
+	}()
+
+	<-started
+	cancel()
+	if err := <-respErr; err != nil {
+		t.Fatalf("in-flight request failed: %v", err)
+	}
+	if err := <-runErr; err != nil {
+		t.Fatalf("run: %v", err)
+	}
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("condition not met")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestManager_ShutdownDelayKeepsServingWhileUnready(t *testing.T) {
+	const delay = 150 * time.Millisecond
+	m := NewManager(time.Second)
+	m.ShutdownDelay = delay
+	var unreadyAt, stoppedAt time.Time
+	m.Add(ComponentFunc("web", func(ctx context.Context) error {
+		<-ctx.Done()
+		stoppedAt = time.Now()
+		return nil
+	}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- m.Run(ctx) }()
+	waitFor(t, m.Ready)
+	cancel()
+	waitFor(t, func() bool { return !m.Ready() })
+	unreadyAt = time.Now()
+
+	if err := <-done; err != nil {
+		t.Fatalf("expected clean shutdown, got %v", err)
+	}
+	if gap := stoppedAt.Sub(unreadyAt); gap < delay-20*time.Millisecond {
+		t.Fatalf("component stopped %s after readiness flipped, want at least ~%s", gap, delay)
+	}
+}
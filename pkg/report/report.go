@@ -0,0 +1,156 @@
+// Package report lets init agents signal completion of a startup component over
+// HTTPS, authenticated by their service account token, so they need no Pod or
+// Node write RBAC.
+package report
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/klog/v2"
+
+	startup "github.com/zhangchl007/nodetaintshandler/pkg/startup"
+)
+
+// Service account token extras identifying the pod a bound token belongs to.
+const (
+	extraNodeName = "authentication.kubernetes.io/node-name"
+	extraPodName  = "authentication.kubernetes.io/pod-name"
+	extraPodUID   = "authentication.kubernetes.io/pod-uid"
+)
+
+// ReadyPath is the route pattern served by Register.
+const ReadyPath = "POST /v1/nodes/{node}/components/{name}/ready"
+
+// Reporter records component readiness on Nodes for callers proven (via
+// TokenReview) to run in a pod on that node.
+type Reporter struct {
+	Client kubernetes.Interface
+	// Audiences, when set, are required in the reviewed token.
+	Audiences []string
+	// ServiceAccounts, when set, are the "<namespace>/<name>" accounts allowed to
+	// report. Any pod on the node can mint a token for the audience, so without
+	// this a workload could mark components it does not own ready.
+	ServiceAccounts []string
+}
+
+// Ready handles ReadyPath.
+func (rp *Reporter) Ready(w http.ResponseWriter, r *http.Request) {
+	nodeName, component := r.PathValue("node"), r.PathValue("name")
+	if errs := validation.IsDNS1123Label(component); len(errs) > 0 {
+		http.Error(w, "invalid component name: "+strings.Join(errs, "; "), http.StatusBadRequest)
+		return
+	}
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || token == "" {
+		http.Error(w, "bearer token required", http.StatusUnauthorized)
+		return
+	}
+	user, err := rp.authenticate(r.Context(), token)
+	if err != nil {
+		klog.Warningf("Readiness report for node %s: %v", nodeName, err)
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if err := rp.authorize(r.Context(), user, nodeName); err != nil {
+		klog.Warningf("Readiness report from %s for node %s denied: %v", user.Username, nodeName, err)
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	if err := rp.record(r.Context(), nodeName, component); err != nil {
+		if apierrors.IsNotFound(err) {
+			http.Error(w, "node not found", http.StatusNotFound)
+			return
+		}
+		klog.Errorf("Record component %s ready on node %s: %v", component, nodeName, err)
+		http.Error(w, "record failed", http.StatusInternalServerError)
+		return
+	}
+	klog.Infof("Component %s reported ready on node %s by %s", component, nodeName, user.Username)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (rp *Reporter) authenticate(ctx context.Context, token string) (authenticationv1.UserInfo, error) {
+	review, err := rp.Client.AuthenticationV1().TokenReviews().Create(ctx, &authenticationv1.TokenReview{
+		Spec: authenticationv1.TokenReviewSpec{Token: token, Audiences: rp.Audiences},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		return authenticationv1.UserInfo{}, fmt.Errorf("token review: %w", err)
+	}
+	if !review.Status.Authenticated {
+		return authenticationv1.UserInfo{}, fmt.Errorf("token not authenticated: %s", review.Status.Error)
+	}
+	return review.Status.User, nil
+}
+
+// authorize requires a pod-bound service account token for a pod on nodeName.
+// Tokens carry the node name directly on recent clusters; otherwise the bound
+// pod is looked up and its UID and node compared.
+func (rp *Reporter) authorize(ctx context.Context, user authenticationv1.UserInfo, nodeName string) error {
+	if !rp.reporter(user.Username) {
+		return fmt.Errorf("%s may not report readiness", user.Username)
+	}
+	if v := user.Extra[extraNodeName]; len(v) == 1 {
+		if v[0] != nodeName {
+			return fmt.Errorf("token is bound to a pod on node %s", v[0])
+		}
+		return nil
+	}
+	podName, podUID := user.Extra[extraPodName], user.Extra[extraPodUID]
+	parts := strings.Split(user.Username, ":")
+	if len(podName) != 1 || len(podUID) != 1 || len(parts) != 4 || parts[0] != "system" || parts[1] != "serviceaccount" {
+		return fmt.Errorf("a pod-bound service account token is required")
+	}
+	pod, err := rp.Client.CoreV1().Pods(parts[2]).Get(ctx, podName[0], metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("look up bound pod: %w", err)
+	}
+	if string(pod.UID) != podUID[0] {
+		return fmt.Errorf("bound pod %s/%s no longer exists", parts[2], podName[0])
+	}
+	if pod.Spec.NodeName != nodeName {
+		return fmt.Errorf("pod %s/%s runs on node %q", pod.Namespace, pod.Name, pod.Spec.NodeName)
+	}
+	return nil
+}
+
+// reporter reports whether username is one of rp.ServiceAccounts (any, when unset).
+func (rp *Reporter) reporter(username string) bool {
+	if len(rp.ServiceAccounts) == 0 {
+		return true
+	}
+	for _, sa := range rp.ServiceAccounts {
+		if ns, name, ok := strings.Cut(sa, "/"); ok && username == "system:serviceaccount:"+ns+":"+name {
+			return true
+		}
+	}
+	return false
+}
+
+func (rp *Reporter) record(ctx context.Context, nodeName, component string) error {
+	patch, _ := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]string{
+				startup.ComponentReadyAnnotation(component): time.Now().UTC().Format(time.RFC3339),
+			},
+		},
+	})
+	_, err := rp.Client.CoreV1().Nodes().Patch(ctx, nodeName, types.MergePatchType, patch, metav1.PatchOptions{})
+	return err
+}
+
+// Register registers the readiness reporting endpoint on a mux.
+func Register(mux *http.ServeMux, rp *Reporter) {
+	mux.HandleFunc(ReadyPath, rp.Ready)
+	klog.Info("Readiness report handler registered (/v1/nodes/{node}/components/{name}/ready)")
+}
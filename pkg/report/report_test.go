@@ -0,0 +1,153 @@
+package report
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	ktesting "k8s.io/client-go/testing"
+
+	startup "github.com/zhangchl007/nodetaintshandler/pkg/startup"
+)
+
+// reviewAs makes every TokenReview authenticate as user (token "bad" fails).
+func reviewAs(client *fake.Clientset, user authenticationv1.UserInfo) {
+	client.PrependReactor("create", "tokenreviews", func(a ktesting.Action) (bool, runtime.Object, error) {
+		tr := a.(ktesting.CreateAction).GetObject().(*authenticationv1.TokenReview)
+		if tr.Spec.Token == "bad" {
+			tr.Status = authenticationv1.TokenReviewStatus{Error: "invalid token"}
+			return true, tr, nil
+		}
+		tr.Status = authenticationv1.TokenReviewStatus{Authenticated: true, User: user}
+		return true, tr, nil
+	})
+}
+
+func post(rp *Reporter, path, token string) *httptest.ResponseRecorder {
+	mux := http.NewServeMux()
+	Register(mux, rp)
+	req := httptest.NewRequest(http.MethodPost, path, nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+	return rr
+}
+
+func saUser(extra map[string]authenticationv1.ExtraValue) authenticationv1.UserInfo {
+	return authenticationv1.UserInfo{Username: "system:serviceaccount:kube-system:init", Extra: extra}
+}
+
+func TestReady_RecordsAnnotationForPodOnNode(t *testing.T) {
+	client := fake.NewSimpleClientset(&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "n1"}})
+	reviewAs(client, saUser(map[string]authenticationv1.ExtraValue{extraNodeName: {"n1"}}))
+	rr := post(&Reporter{Client: client}, "/v1/nodes/n1/components/gpu-driver/ready", "tok")
+	if rr.Code != http.StatusNoContent {
+		t.Fatalf("code=%d body=%s", rr.Code, rr.Body.String())
+	}
+	n, _ := client.CoreV1().Nodes().Get(context.TODO(), "n1", metav1.GetOptions{})
+	if n.Annotations[startup.ComponentReadyAnnotation("gpu-driver")] == "" {
+		t.Fatalf("annotation not recorded: %v", n.Annotations)
+	}
+}
+
+func TestReady_FallsBackToBoundPodLookup(t *testing.T) {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "init-x", Namespace: "kube-system", UID: "pod-uid"},
+		Spec:       corev1.PodSpec{NodeName: "n1"},
+	}
+	client := fake.NewSimpleClientset(&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "n1"}}, &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "n2"}}, pod)
+	reviewAs(client, saUser(map[string]authenticationv1.ExtraValue{extraPodName: {"init-x"}, extraPodUID: {"pod-uid"}}))
+	rp := &Reporter{Client: client}
+	if rr := post(rp, "/v1/nodes/n1/components/init/ready", "tok"); rr.Code != http.StatusNoContent {
+		t.Fatalf("code=%d body=%s", rr.Code, rr.Body.String())
+	}
+	if rr := post(rp, "/v1/nodes/n2/components/init/ready", "tok"); rr.Code != http.StatusForbidden {
+		t.Fatalf("other node: code=%d", rr.Code)
+	}
+}
+
+func TestReady_Rejections(t *testing.T) {
+	client := fake.NewSimpleClientset(&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "n1"}})
+	reviewAs(client, saUser(map[string]authenticationv1.ExtraValue{extraNodeName: {"n1"}}))
+	rp := &Reporter{Client: client}
+	cases := []struct {
+		name, path, token string
+		want              int
+	}{
+		{"no token", "/v1/nodes/n1/components/init/ready", "", http.StatusUnauthorized},
+		{"invalid token", "/v1/nodes/n1/components/init/ready", "bad", http.StatusUnauthorized},
+		{"other node", "/v1/nodes/n2/components/init/ready", "tok", http.StatusForbidden},
+		{"bad component", "/v1/nodes/n1/components/Not_Valid/ready", "tok", http.StatusBadRequest},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if rr := post(rp, tc.path, tc.token); rr.Code != tc.want {
+				t.Fatalf("code=%d want %d (%s)", rr.Code, tc.want, rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestReady_UnboundTokenForbidden(t *testing.T) {
+	client := fake.NewSimpleClientset(&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "n1"}})
+	reviewAs(client, authenticationv1.UserInfo{Username: "alice"})
+	if rr := post(&Reporter{Client: client}, "/v1/nodes/n1/components/init/ready", "tok"); rr.Code != http.StatusForbidden {
+		t.Fatalf("code=%d", rr.Code)
+	}
+}
+
+func TestReady_RequiresAudience(t *testing.T) {
+	client := fake.NewSimpleClientset(&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "n1"}})
+	// Like the apiserver: a token minted for "report" authenticates only when
+	// the review asks for that audience.
+	client.PrependReactor("create", "tokenreviews", func(a ktesting.Action) (bool, runtime.Object, error) {
+		tr := a.(ktesting.CreateAction).GetObject().(*authenticationv1.TokenReview)
+		for _, aud := range tr.Spec.Audiences {
+			if aud == "report" {
+				tr.Status = authenticationv1.TokenReviewStatus{Authenticated: true, Audiences: []string{aud},
+					User: saUser(map[string]authenticationv1.ExtraValue{extraNodeName: {"n1"}})}
+				return true, tr, nil
+			}
+		}
+		tr.Status = authenticationv1.TokenReviewStatus{Error: "token audiences are invalid"}
+		return true, tr, nil
+	})
+	if rr := post(&Reporter{Client: client, Audiences: []string{"report"}}, "/v1/nodes/n1/components/init/ready", "tok"); rr.Code != http.StatusNoContent {
+		t.Fatalf("matching audience: code=%d body=%s", rr.Code, rr.Body.String())
+	}
+	if rr := post(&Reporter{Client: client}, "/v1/nodes/n1/components/init/ready", "tok"); rr.Code != http.StatusUnauthorized {
+		t.Fatalf("apiserver audience: code=%d", rr.Code)
+	}
+}
+
+func TestReady_OnlyConfiguredServiceAccounts(t *testing.T) {
+	client := fake.NewSimpleClientset(&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "n1"}})
+	// A workload pod on the same node with a token minted for the report audience.
+	reviewAs(client, authenticationv1.UserInfo{
+		Username: "system:serviceaccount:default:web",
+		Extra:    map[string]authenticationv1.ExtraValue{extraNodeName: {"n1"}},
+	})
+	rp := &Reporter{Client: client, ServiceAccounts: []string{"kube-system/init"}}
+	if rr := post(rp, "/v1/nodes/n1/components/init/ready", "tok"); rr.Code != http.StatusForbidden {
+		t.Fatalf("workload token: code=%d", rr.Code)
+	}
+	n, _ := client.CoreV1().Nodes().Get(context.TODO(), "n1", metav1.GetOptions{})
+	if len(n.Annotations) != 0 {
+		t.Fatalf("workload report recorded: %v", n.Annotations)
+	}
+
+	agent := fake.NewSimpleClientset(&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "n1"}})
+	reviewAs(agent, saUser(map[string]authenticationv1.ExtraValue{extraNodeName: {"n1"}}))
+	rp.Client = agent
+	if rr := post(rp, "/v1/nodes/n1/components/init/ready", "tok"); rr.Code != http.StatusNoContent {
+		t.Fatalf("agent token: code=%d body=%s", rr.Code, rr.Body.String())
+	}
+}
@@ -0,0 +1,248 @@
+package startup
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/klog/v2"
+)
+
+// Event reasons for the fleet breaker.
+const (
+	EventBreakerTripped = "StartupBreakerTripped"
+	EventBreakerRelease = "StartupBreakerRelease"
+)
+
+// BreakerConfig bounds how much of the fleet may sit gated. When a pool has
+// more stuck nodes than allowed, the breaker trips: new nodes in that pool are
+// no longer tainted and, with ReleaseOnTrip, gated ones are released.
+type BreakerConfig struct {
+	// MaxGatedPercent of a pool's nodes may be stuck (0 disables the check).
+	MaxGatedPercent float64
+	// MaxGatedNodes per pool may be stuck (0 disables the check).
+	MaxGatedNodes int
+	// GatedFor is how long a node must be gated to count as stuck.
+	GatedFor time.Duration
+	// PoolLabel groups nodes into pools by this label's value; empty means one cluster-wide pool.
+	PoolLabel string
+	// ReleaseOnTrip releases every gated (not held) node of a tripped pool.
+	ReleaseOnTrip bool
+	// Cooldown keeps a pool tripped until it has stayed under budget this long.
+	Cooldown time.Duration
+	// Interval between evaluations (default 30s).
+	Interval time.Duration
+}
+
+func (cfg BreakerConfig) enabled() bool {
+	return cfg.MaxGatedPercent > 0 || cfg.MaxGatedNodes > 0
+}
+
+// WithBreaker enables the gated-fleet circuit breaker.
+func WithBreaker(cfg BreakerConfig) Option {
+	return func(c *Controller) {
+		if !cfg.enabled() {
+			c.breaker = nil
+			return
+		}
+		if cfg.Interval <= 0 {
+			cfg.Interval = 30 * time.Second
+		}
+		c.breaker = &breaker{cfg: cfg, tripped: map[string]*trip{}}
+	}
+}
+
+type trip struct {
+	reason     string
+	clearSince time.Time
+	// stuck are the pool's stuck nodes as of the last evaluation.
+	stuck []*corev1.Node
+	// reported is set once a leader has emitted the events (and released nodes).
+	reported bool
+}
+
+type breaker struct {
+	cfg     BreakerConfig
+	mu      sync.Mutex
+	tripped map[string]*trip
+}
+
+func (b *breaker) pool(node *corev1.Node) string {
+	if b.cfg.PoolLabel == "" {
+		return ""
+	}
+	return node.Labels[b.cfg.PoolLabel]
+}
+
+func poolName(pool string) string {
+	if pool == "" {
+		return "cluster"
+	}
+	return "pool " + pool
+}
+
+// GatedSince is when the node was gated: startup.k8s.io/taintedAt, else its creation.
+func GatedSince(node *corev1.Node) time.Time {
+	if t, err := time.Parse(time.RFC3339, node.Annotations[NodeTaintedAtAnnotation]); err == nil {
+		return t
+	}
+	return node.CreationTimestamp.Time
+}
+
+// stuck reports whether a node counts against the budget: gated (not on hold) for longer than GatedFor.
+func (b *breaker) stuck(node *corev1.Node, now time.Time) bool {
+	return HasStartupTaint(node) && NodeOverride(node) != OverrideHold &&
+		now.Sub(GatedSince(node)) > b.cfg.GatedFor
+}
+
+// update recomputes every pool and returns pools that tripped in this round with their stuck nodes.
+func (b *breaker) update(nodes []*corev1.Node, now time.Time) map[string][]*corev1.Node {
+	total := map[string]int{}
+	stuck := map[string][]*corev1.Node{}
+	for _, n := range nodes {
+		// Only nodes the webhook would gate count towards the pool's size.
+		if ExemptSystemNode(n) {
+			continue
+		}
+		p := b.pool(n)
+		total[p]++
+		if b.stuck(n, now) {
+			stuck[p] = append(stuck[p], n)
+		}
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	tripped := map[string][]*corev1.Node{}
+	for p, t := range total {
+		s := len(stuck[p])
+		var reason string
+		switch {
+		case b.cfg.MaxGatedNodes > 0 && s > b.cfg.MaxGatedNodes:
+			reason = fmt.Sprintf("%d nodes gated longer than %s (max %d)", s, b.cfg.GatedFor, b.cfg.MaxGatedNodes)
+		case b.cfg.MaxGatedPercent > 0 && float64(s)*100/float64(t) > b.cfg.MaxGatedPercent:
+			reason = fmt.Sprintf("%d of %d nodes gated longer than %s (max %g%%)", s, t, b.cfg.GatedFor, b.cfg.MaxGatedPercent)
+		}
+		cur := b.tripped[p]
+		switch {
+		case reason != "" && cur == nil:
+			b.tripped[p] = &trip{reason: reason, stuck: stuck[p]}
+			tripped[p] = stuck[p]
+		case reason != "":
+			cur.reason, cur.clearSince, cur.stuck = reason, time.Time{}, stuck[p]
+		case cur != nil && cur.clearSince.IsZero():
+			cur.clearSince = now
+		case cur != nil && now.Sub(cur.clearSince) >= b.cfg.Cooldown:
+			delete(b.tripped, p)
+			klog.Infof("Startup breaker for %s reset (under budget for %s)", poolName(p), b.cfg.Cooldown)
+		}
+	}
+	return tripped
+}
+
+// unreported returns the stuck nodes of every tripped pool not reported yet and
+// marks those trips reported.
+func (b *breaker) unreported() map[string][]*corev1.Node {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	out := map[string][]*corev1.Node{}
+	for p, t := range b.tripped {
+		if !t.reported {
+			t.reported = true
+			out[p] = t.stuck
+		}
+	}
+	return out
+}
+
+func (b *breaker) allow(node *corev1.Node) (bool, string) {
+	return b.allowPool(b.pool(node))
+}
+
+func (b *breaker) allowPool(p string) (bool, string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if t := b.tripped[p]; t != nil {
+		return false, fmt.Sprintf("startup breaker tripped for %s: %s", poolName(p), t.reason)
+	}
+	return true, ""
+}
+
+// AllowTaint reports whether a node may be gated; false while the fleet breaker
+// for its pool is tripped (the webhook then admits new nodes untainted).
+func (c *Controller) AllowTaint(node *corev1.Node) (bool, string) {
+	if c.breaker == nil {
+		return true, ""
+	}
+	return c.breaker.allow(node)
+}
+
+// runBreaker evaluates the budget on every replica (so each webhook sees it);
+// only the leader emits events and releases nodes.
+func (c *Controller) runBreaker(ctx context.Context) {
+	wait.UntilWithContext(ctx, func(ctx context.Context) {
+		nodes, err := c.nodeLister.List(labels.Everything())
+		if err != nil {
+			klog.Warningf("breaker list nodes: %v", err)
+			return
+		}
+		c.evaluateBreaker(ctx, nodes, time.Now())
+	}, c.breaker.cfg.Interval)
+}
+
+// evaluateBreaker updates the pools and, when leading, acts on every trip not
+// reported yet, including trips seen while this replica was a follower.
+func (c *Controller) evaluateBreaker(ctx context.Context, nodes []*corev1.Node, now time.Time) {
+	for p := range c.breaker.update(nodes, now) {
+		c.metrics.breakerTrips.WithLabelValues(p).Inc()
+	}
+	if !c.leading() {
+		return
+	}
+	for p, stuck := range c.breaker.unreported() {
+		c.onBreakerTrip(ctx, p, nodes, stuck)
+	}
+}
+
+func (c *Controller) onBreakerTrip(ctx context.Context, pool string, nodes, stuck []*corev1.Node) {
+	_, reason := c.breaker.allowPool(pool)
+	klog.Errorf("%s; new nodes are admitted without the startup taint", reason)
+	for _, n := range stuck {
+		c.eventf(n, corev1.EventTypeWarning, EventBreakerTripped, "%s; new nodes are no longer gated", reason)
+	}
+	if !c.breaker.cfg.ReleaseOnTrip {
+		return
+	}
+	for _, n := range nodes {
+		if c.breaker.pool(n) != pool || !HasStartupTaint(n) || NodeOverride(n) == OverrideHold {
+			continue
+		}
+		changed, err := Release(ctx, c.client, n.Name, "breaker:"+poolName(pool))
+		if err != nil {
+			klog.Warningf("breaker release node %s: %v", n.Name, err)
+			continue
+		}
+		if changed {
+			c.metrics.releases.WithLabelValues(releasedBreaker).Inc()
+			c.forgetRelease(n.Name)
+			c.eventf(n, corev1.EventTypeWarning, EventBreakerRelease, "Startup taint removed by tripped breaker: %s", reason)
+		}
+	}
+}
+
+// leading reports whether this replica may write (no leader election, or leader).
+func (c *Controller) leading() bool {
+	if c.leaderGate == nil {
+		return true
+	}
+	select {
+	case <-c.leaderGate:
+		return true
+	default:
+		return false
+	}
+}
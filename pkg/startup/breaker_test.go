@@ -0,0 +1,203 @@
+package startup
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+)
+
+func poolNode(name, pool string, age time.Duration, tainted bool) *corev1.Node {
+	n := makeNode(name)
+	if tainted {
+		n.Spec.Taints = []corev1.Taint{StartupTaint}
+	}
+	n.Labels = map[string]string{"pool": pool}
+	n.CreationTimestamp = metav1.NewTime(time.Now().Add(-age))
+	return n
+}
+
+func TestBreaker_TripsPerPoolAndResetsAfterCooldown(t *testing.T) {
+	c, _ := newControllerWith()
+	WithBreaker(BreakerConfig{MaxGatedPercent: 50, GatedFor: 10 * time.Minute, PoolLabel: "pool", Cooldown: 5 * time.Minute})(c)
+
+	nodes := []*corev1.Node{
+		poolNode("a1", "a", time.Hour, true),
+		poolNode("a2", "a", time.Hour, true),
+		poolNode("a3", "a", time.Hour, false),
+		poolNode("b1", "b", time.Hour, true),
+		poolNode("b2", "b", time.Minute, true), // too young to count
+		poolNode("b3", "b", time.Hour, false),
+	}
+	held := poolNode("b4", "b", time.Hour, true)
+	held.Annotations = map[string]string{HoldAnnotation: "true"} // held nodes do not count
+	nodes = append(nodes, held)
+
+	now := time.Now()
+	tripped := c.breaker.update(nodes, now)
+	if len(tripped) != 1 || len(tripped["a"]) != 2 {
+		t.Fatalf("expected only pool a tripped with 2 stuck nodes, got %v", tripped)
+	}
+	if ok, reason := c.AllowTaint(poolNode("a9", "a", 0, false)); ok || !strings.Contains(reason, "pool a") {
+		t.Fatalf("pool a should refuse new taints, got ok=%v reason=%q", ok, reason)
+	}
+	if ok, _ := c.AllowTaint(poolNode("b9", "b", 0, false)); !ok {
+		t.Fatal("pool b is within budget")
+	}
+	if again := c.breaker.update(nodes, now.Add(time.Minute)); len(again) != 0 {
+		t.Fatalf("already tripped pool reported again: %v", again)
+	}
+
+	// Under budget again: stays tripped until the cooldown has passed.
+	nodes[0].Spec.Taints = nil
+	c.breaker.update(nodes, now.Add(2*time.Minute))
+	c.breaker.update(nodes, now.Add(6*time.Minute))
+	if ok, _ := c.AllowTaint(nodes[0]); ok {
+		t.Fatal("breaker reset before cooldown")
+	}
+	c.breaker.update(nodes, now.Add(8*time.Minute))
+	if ok, _ := c.AllowTaint(nodes[0]); !ok {
+		t.Fatal("breaker should reset after cooldown")
+	}
+}
+
+func TestBreaker_NodeCountAndReleaseOnTrip(t *testing.T) {
+	n1 := poolNode("n1", "", time.Hour, true)
+	n2 := poolNode("n2", "", time.Hour, true)
+	n3 := poolNode("n3", "", time.Hour, false)
+	c, client := newControllerWith([]runtime.Object{n1, n2, n3}...)
+	rec := record.NewFakeRecorder(10)
+	WithRecorder(rec)(c)
+	WithBreaker(BreakerConfig{MaxGatedNodes: 1, GatedFor: time.Minute, ReleaseOnTrip: true})(c)
+
+	nodes := []*corev1.Node{n1, n2, n3}
+	tripped := c.breaker.update(nodes, time.Now())
+	stuck, ok := tripped[""]
+	if !ok {
+		t.Fatalf("cluster pool should trip, got %v", tripped)
+	}
+	c.onBreakerTrip(ctx(), "", nodes, stuck)
+
+	for _, name := range []string{"n1", "n2"} {
+		got, _ := client.CoreV1().Nodes().Get(ctx(), name, metav1.GetOptions{})
+		if HasStartupTaint(got) {
+			t.Fatalf("%s should be released by the breaker", name)
+		}
+		if got.Annotations[NodeReleasedByAnnotation] != "breaker:cluster" {
+			t.Fatalf("%s releasedBy=%q", name, got.Annotations[NodeReleasedByAnnotation])
+		}
+	}
+	var trips int
+	for len(rec.Events) > 0 {
+		if strings.Contains(<-rec.Events, EventBreakerTripped) {
+			trips++
+		}
+	}
+	if trips != 2 {
+		t.Fatalf("expected a trip event per stuck node, got %d", trips)
+	}
+
+	// Dropped-taint re-gating and backfill are suppressed while tripped.
+	names, err := c.Backfill(ctx(), true)
+	if err != nil || len(names) != 0 {
+		t.Fatalf("backfill while tripped: %v %v", names, err)
+	}
+}
+
+func TestWithBreaker_DisabledWithoutBudget(t *testing.T) {
+	c, _ := newControllerWith()
+	WithBreaker(BreakerConfig{GatedFor: time.Minute})(c)
+	if c.breaker != nil {
+		t.Fatal("breaker without a budget should be disabled")
+	}
+	if ok, _ := c.AllowTaint(makeNode("n")); !ok {
+		t.Fatal("no breaker allows every taint")
+	}
+}
+
+func TestGatedSince(t *testing.T) {
+	n := poolNode("n1", "", time.Hour, true)
+	if !GatedSince(n).Equal(n.CreationTimestamp.Time) {
+		t.Fatal("without taintedAt, gating starts at creation")
+	}
+	at := time.Now().Add(-time.Minute).UTC().Truncate(time.Second)
+	n.Annotations = map[string]string{NodeTaintedAtAnnotation: at.Format(time.RFC3339)}
+	if !GatedSince(n).Equal(at) {
+		t.Fatalf("GatedSince=%v, want %v", GatedSince(n), at)
+	}
+}
+
+func TestBreaker_PoolSizeExcludesSystemNodes(t *testing.T) {
+	c, _ := newControllerWith()
+	WithBreaker(BreakerConfig{MaxGatedPercent: 30, GatedFor: time.Minute})(c)
+
+	system := func(name string, held bool) *corev1.Node {
+		n := poolNode(name, "", time.Hour, false)
+		n.Labels[AKSModeLabel] = "system"
+		if held {
+			n.Annotations = map[string]string{HoldAnnotation: "true"}
+		}
+		return n
+	}
+	nodes := []*corev1.Node{
+		poolNode("u1", "", time.Hour, true),
+		poolNode("u2", "", time.Hour, false),
+		system("s1", false),
+		system("s2", false),
+		system("s3", false),
+		system("s4", true), // held system nodes are gated, so they count
+	}
+	c.breaker.update(nodes, time.Now())
+	if ok, reason := c.AllowTaint(nodes[0]); ok || !strings.Contains(reason, "1 of 3 nodes") {
+		t.Fatalf("expected trip over 3 eligible nodes, got ok=%v reason=%q", ok, reason)
+	}
+}
+
+func TestBreaker_ReportsTripOnceLeading(t *testing.T) {
+	n1 := poolNode("n1", "", time.Hour, true)
+	n2 := poolNode("n2", "", time.Hour, true)
+	c, client := newControllerWith([]runtime.Object{n1, n2}...)
+	rec := record.NewFakeRecorder(10)
+	WithRecorder(rec)(c)
+	WithBreaker(BreakerConfig{MaxGatedNodes: 1, GatedFor: time.Minute, ReleaseOnTrip: true})(c)
+	gate := make(chan struct{})
+	c.leaderGate = gate
+
+	nodes := []*corev1.Node{n1, n2}
+	now := time.Now()
+	c.evaluateBreaker(ctx(), nodes, now)
+	if ok, _ := c.AllowTaint(n1); ok {
+		t.Fatal("follower should still trip its own breaker")
+	}
+	if len(rec.Events) != 0 {
+		t.Fatalf("follower reported the trip")
+	}
+
+	// Becomes leader while the pool is still tripped: report and release once.
+	close(gate)
+	c.evaluateBreaker(ctx(), nodes, now.Add(time.Minute))
+	for _, name := range []string{"n1", "n2"} {
+		if got, _ := client.CoreV1().Nodes().Get(ctx(), name, metav1.GetOptions{}); HasStartupTaint(got) {
+			t.Fatalf("%s should be released by the new leader", name)
+		}
+	}
+	trips := 0
+	for len(rec.Events) > 0 {
+		if strings.Contains(<-rec.Events, EventBreakerTripped) {
+			trips++
+		}
+	}
+	if trips != 2 {
+		t.Fatalf("expected a trip event per stuck node, got %d", trips)
+	}
+	c.evaluateBreaker(ctx(), nodes, now.Add(2*time.Minute))
+	for len(rec.Events) > 0 {
+		if e := <-rec.Events; strings.Contains(e, EventBreakerTripped) {
+			t.Fatalf("trip reported twice: %s", e)
+		}
+	}
+}
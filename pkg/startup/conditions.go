@@ -0,0 +1,89 @@
+package startup
+
+import (
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// ConditionRequirement is a Node condition that must report Status before the
+// startup taint is removed (e.g. ImagesPrefetched=True set by a host agent).
+type ConditionRequirement struct {
+	Type   corev1.NodeConditionType
+	Status corev1.ConditionStatus
+}
+
+func (r ConditionRequirement) String() string {
+	return fmt.Sprintf("%s=%s", r.Type, r.Status)
+}
+
+// ParseConditionRequirements parses "Type[=Status],..." (status defaults to True).
+func ParseConditionRequirements(s string) ([]ConditionRequirement, error) {
+	var out []ConditionRequirement
+	for _, item := range strings.Split(s, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		typ, status, ok := strings.Cut(item, "=")
+		if !ok {
+			status = string(corev1.ConditionTrue)
+		}
+		switch corev1.ConditionStatus(status) {
+		case corev1.ConditionTrue, corev1.ConditionFalse, corev1.ConditionUnknown:
+		default:
+			return nil, fmt.Errorf("condition %s: status must be True, False or Unknown", typ)
+		}
+		if typ == "" {
+			return nil, fmt.Errorf("condition %q: type required", item)
+		}
+		out = append(out, ConditionRequirement{Type: corev1.NodeConditionType(typ), Status: corev1.ConditionStatus(status)})
+	}
+	return out, nil
+}
+
+// RequireNodeConditions is satisfied when the node reports every requirement.
+func RequireNodeConditions(reqs ...ConditionRequirement) ReadinessChecker {
+	checkers := make([]ReadinessChecker, 0, len(reqs))
+	for _, r := range reqs {
+		checkers = append(checkers, NodeCondition(r.Type, r.Status))
+	}
+	return AllOf(checkers...)
+}
+
+// WithRequiredNodeConditions adds Node conditions every policy must also satisfy.
+func WithRequiredNodeConditions(reqs []ConditionRequirement) Option {
+	return func(c *Controller) {
+		c.requiredConditions = reqs
+	}
+}
+
+// WithDefaultReadiness replaces the readiness check of the default policy; nil
+// drops the init pod check so only required Node conditions gate the node. With
+// neither, the default policy falls back to PodReady (see readinessFor).
+func WithDefaultReadiness(r ReadinessChecker) Option {
+	return func(c *Controller) {
+		c.defaultPolicy.Readiness = r
+	}
+}
+
+// readinessFor combines the policy's check with the controller-wide required
+// conditions. A policy left without any check uses PodReady: an empty AllOf
+// would be vacuously ready and release every node at once.
+func (c *Controller) readinessFor(p Policy) ReadinessChecker {
+	var checkers []ReadinessChecker
+	if p.Readiness != nil {
+		checkers = append(checkers, p.Readiness)
+	}
+	if len(c.requiredConditions) > 0 {
+		checkers = append(checkers, RequireNodeConditions(c.requiredConditions...))
+	}
+	if len(checkers) == 0 {
+		return PodReady()
+	}
+	if len(checkers) == 1 {
+		return checkers[0]
+	}
+	return AllOf(checkers...)
+}
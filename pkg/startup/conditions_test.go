@@ -0,0 +1,101 @@
+package startup
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestParseConditionRequirements(t *testing.T) {
+	reqs, err := ParseConditionRequirements("ImagesPrefetched, GPUDriverReady=True,DiskPressure=False")
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	want := []ConditionRequirement{
+		{Type: "ImagesPrefetched", Status: corev1.ConditionTrue},
+		{Type: "GPUDriverReady", Status: corev1.ConditionTrue},
+		{Type: "DiskPressure", Status: corev1.ConditionFalse},
+	}
+	if len(reqs) != len(want) {
+		t.Fatalf("got %v", reqs)
+	}
+	for i := range want {
+		if reqs[i] != want[i] {
+			t.Fatalf("reqs[%d]=%v want %v", i, reqs[i], want[i])
+		}
+	}
+	for _, bad := range []string{"X=yes", "=True"} {
+		if _, err := ParseConditionRequirements(bad); err == nil {
+			t.Fatalf("expected error for %q", bad)
+		}
+	}
+}
+
+func withCondition(n *corev1.Node, typ corev1.NodeConditionType, status corev1.ConditionStatus) *corev1.Node {
+	out := n.DeepCopy()
+	out.Status.Conditions = append(out.Status.Conditions, corev1.NodeCondition{Type: typ, Status: status})
+	return out
+}
+
+func TestRequiredConditions_CombinedWithInitPod(t *testing.T) {
+	n := makeNode("n1", StartupTaint)
+	ready := podWith("init", "n1", labeledStartup(), map[string]string{StartPodReadyAnnotation: "true"}, nil, nil)
+	c, client := newControllerWith(n, ready)
+	WithRequiredNodeConditions([]ConditionRequirement{{Type: "ImagesPrefetched", Status: corev1.ConditionTrue}})(c)
+
+	if _, pending, _ := c.evaluate(n); len(pending) != 1 || pending[0] != "node condition ImagesPrefetched not reported" {
+		t.Fatalf("unexpected pending %v", pending)
+	}
+	c.handleNode(n)
+	if got, _ := client.CoreV1().Nodes().Get(ctx(), "n1", metav1.GetOptions{}); !HasStartupTaint(got) {
+		t.Fatalf("taint removed before condition reported")
+	}
+
+	// Condition transition arrives via the node informer's update handler.
+	c.handleNodeUpdate(n, withCondition(n, "ImagesPrefetched", corev1.ConditionTrue))
+	if got, _ := client.CoreV1().Nodes().Get(ctx(), "n1", metav1.GetOptions{}); HasStartupTaint(got) {
+		t.Fatalf("taint not removed after condition became True")
+	}
+}
+
+func TestRequiredConditions_Alone(t *testing.T) {
+	n := withCondition(makeNode("n1", StartupTaint), "GPUDriverReady", corev1.ConditionTrue)
+	c, _ := newControllerWith(n)
+	WithDefaultReadiness(nil)(c)
+	WithRequiredNodeConditions([]ConditionRequirement{{Type: "GPUDriverReady", Status: corev1.ConditionTrue}})(c)
+	if ok, _, _ := c.evaluate(n); !ok {
+		t.Fatalf("expected ready without any init pod")
+	}
+}
+
+func TestDefaultReadinessNil_FallsBackToPodReady(t *testing.T) {
+	n := makeNode("n1", StartupTaint)
+	c, client := newControllerWith(n)
+	WithDefaultReadiness(nil)(c)
+	if ok, _, _ := c.evaluate(n); ok {
+		t.Fatalf("expected not ready without any readiness check")
+	}
+	c.handleNode(n)
+	if got, _ := client.CoreV1().Nodes().Get(ctx(), "n1", metav1.GetOptions{}); !HasStartupTaint(got) {
+		t.Fatalf("taint removed with an empty check set")
+	}
+}
+
+func TestPolicy_RequiredNodeConditionsOnly(t *testing.T) {
+	cfg, err := LoadConfig(writeConfig(t, "policies:\n- name: host\n  requiredNodeConditions: [{type: ImagesPrefetched}]\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	policies, err := cfg.BuildPolicies()
+	if err != nil {
+		t.Fatalf("build: %v", err)
+	}
+	n := makeNode("n1", StartupTaint)
+	if ok, _ := policies[0].Readiness.Ready(ReadinessInput{Node: n}); ok {
+		t.Fatalf("expected not ready without condition")
+	}
+	if ok, r := policies[0].Readiness.Ready(ReadinessInput{Node: withCondition(n, "ImagesPrefetched", corev1.ConditionTrue)}); !ok {
+		t.Fatalf("expected ready: %v", r)
+	}
+}
@@ -3,6 +3,7 @@ package startup
 import corev1 "k8s.io/api/core/v1"
 
 const (
+	// TaintKey is the default startup taint key (see SetTaintKey).
 	TaintKey       = "startup.k8s.io/initializing"
 	TaintValue     = "wait"
 	TaintEffectStr = "NoSchedule"
@@ -13,12 +14,45 @@ const (
 	// Annotation a startup DaemonSet Pod can set when its logic is complete (optional shortcut)
 	StartPodReadyAnnotation = "startup.k8s.io/ready"
 
-	// Annotation the controller sets on the Node after taint removal (auditing)
+	// Annotation the controller sets on the Node after taint removal (auditing; RFC3339,
+	// unix epoch seconds from older releases are still read)
 	NodeStartupCompletedAnnotation = "startup.k8s.io/completedAt"
+	// UID of the Node object the completion applies to (detects re-registration under the same name)
+	NodeStartupCompletedUIDAnnotation = "startup.k8s.io/completedUID"
+	// Comma-separated UIDs of failed init pods counted against the node's retry budget
+	NodeInitFailuresAnnotation = "startup.k8s.io/initFailures"
+	// How long the node was gated (since startup.k8s.io/taintedAt) and how long
+	// since it was created, when the taint was removed (Go duration, e.g. "3m25s")
+	NodeGatingDurationAnnotation  = "startup.k8s.io/gatingDuration"
+	NodeStartupDurationAnnotation = "startup.k8s.io/startupDuration"
+	// What satisfied release: the policy name, then the rules or components that passed
+	NodeSatisfiedByAnnotation = "startup.k8s.io/satisfiedBy"
+	// Who removed the taint by hand (kubectl nodestartup release)
+	NodeReleasedByAnnotation = "startup.k8s.io/releasedBy"
+	// When the startup taint was applied (RFC3339), and under which readiness policy
+	NodeTaintedAtAnnotation = "startup.k8s.io/taintedAt"
+	NodePolicyAnnotation    = "startup.k8s.io/policy"
+	// Version of the webhook that tainted the node at admission
+	NodeWebhookVersionAnnotation = "startup.k8s.io/webhookVersion"
+	// Prefix of Node annotations recording components reported ready by init agents (value: RFC3339 time)
+	ComponentReadyAnnotationPrefix = "component.startup.k8s.io/"
 )
 
+// Node label tracking the gating state: StateGating, StateReady or StateFailed.
+const (
+	StateLabel  = "startup.k8s.io/state"
+	StateGating = "gating"
+	StateReady  = "ready"
+	StateFailed = "failed"
+)
+
+// ComponentReadyAnnotation is the Node annotation recording that component reported ready.
+func ComponentReadyAnnotation(component string) string {
+	return ComponentReadyAnnotationPrefix + component
+}
+
 var StartupTaint = corev1.Taint{
 	Key:    TaintKey,
 	Value:  TaintValue,
-	Effect: corev1.TaintEffectNoSchedule,
+	Effect: corev1.TaintEffect(TaintEffectStr),
 }
@@ -2,31 +2,134 @@ package startup
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"os"
-	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	corelisters "k8s.io/client-go/listers/core/v1"
 	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/client-go/util/retry"
+	"k8s.io/client-go/util/workqueue"
 	"k8s.io/klog/v2"
 )
 
 // Controller watches Nodes with the startup taint and removes it once the init pod on that node is Ready.
 type Controller struct {
-	client     kubernetes.Interface
+	client   kubernetes.Interface
+	workers  int
+	policies []Policy
+	// defaultPolicy applies to nodes no configured policy selects.
+	defaultPolicy      Policy
+	requiredConditions []ConditionRequirement
+	// initRetries is how many failed init pods a node tolerates before it is marked failed.
+	initRetries int
+	// initPodTemplate, when set, is launched per gated node (see WithInitPodTemplate).
+	initPodTemplate *types.NamespacedName
+	recorder        record.EventRecorder
+	// releases, when set, rate-limits taint removals (see WithReleaseLimit).
+	releases *releaseLimiter
+	// breaker, when set, stops gating pools with too many stuck nodes (see WithBreaker).
+	breaker    *breaker
 	podIndexer cache.Indexer
+	nodeLister corelisters.NodeLister
+	queue      workqueue.TypedRateLimitingInterface[string]
+	synced     atomic.Bool
+	// leaderGate, when set, holds back workers (and backfill) until closed.
+	leaderGate <-chan struct{}
+	metrics    *metrics
+
+	mu sync.Mutex
+	// dropped records nodes (by UID) whose startup taint disappeared before completion.
+	dropped map[string]types.UID
+	// overrides remembers the override last reported per node, so events are emitted once.
+	overrides map[types.UID]Override
+}
+
+// Option customises a Controller.
+type Option func(*Controller)
+
+// WithWorkers sets the number of reconcile workers (default 2).
+func WithWorkers(n int) Option {
+	return func(c *Controller) {
+		if n > 0 {
+			c.workers = n
+		}
+	}
+}
+
+// WithPolicies sets readiness policies (first match wins; DefaultPolicy otherwise).
+func WithPolicies(p []Policy) Option {
+	return func(c *Controller) {
+		c.policies = p
+	}
+}
+
+// WithInitRetries sets the failed init pod budget per node (default 3).
+func WithInitRetries(n int) Option {
+	return func(c *Controller) {
+		if n >= 0 {
+			c.initRetries = n
+		}
+	}
+}
+
+// WithRecorder sets the event recorder (default: events sent to the API server from Run).
+func WithRecorder(r record.EventRecorder) Option {
+	return func(c *Controller) {
+		c.recorder = r
+	}
+}
+
+// WithLeaderGate delays writes until gate is closed (leader election). Informers
+// still start immediately so caches are warm on failover.
+func WithLeaderGate(gate <-chan struct{}) Option {
+	return func(c *Controller) {
+		c.leaderGate = gate
+	}
+}
+
+func NewController(client kubernetes.Interface, opts ...Option) *Controller {
+	c := &Controller{client: client, workers: 2, initRetries: 3, defaultPolicy: DefaultPolicy(), dropped: map[string]types.UID{}, overrides: map[types.UID]Override{}, metrics: newMetrics()}
+	for _, o := range opts {
+		o(c)
+	}
+	return c
 }
 
-func NewController(client kubernetes.Interface) *Controller {
-	return &Controller{client: client}
+// HasSynced reports whether the informer caches completed their initial sync.
+func (c *Controller) HasSynced() bool {
+	return c.synced.Load()
 }
 
-func (c *Controller) Run(stop <-chan struct{}) {
+// Run starts informers and workers and blocks until ctx is cancelled. On
+// cancellation no new items are picked up; workers finish their current item
+// before Run returns (callers bound the wait).
+func (c *Controller) Run(ctx context.Context) error {
+	if c.recorder == nil {
+		broadcaster := record.NewBroadcaster(record.WithContext(ctx))
+		broadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{Interface: c.client.CoreV1().Events("")})
+		defer broadcaster.Shutdown()
+		c.recorder = broadcaster.NewRecorder(scheme.Scheme, corev1.EventSource{Component: "nodetaintshandler"})
+	}
+	c.queue = workqueue.NewTypedRateLimitingQueueWithConfig(
+		workqueue.DefaultTypedControllerRateLimiter[string](),
+		workqueue.TypedRateLimitingQueueConfig[string]{Name: "startup-nodes"},
+	)
+
 	factory := informers.NewSharedInformerFactory(c.client, 30*time.Second)
 	nodeInformer := factory.Core().V1().Nodes().Informer()
 	podInformer := factory.Core().V1().Pods().Informer()
@@ -43,18 +146,117 @@ func (c *Controller) Run(stop <-chan struct{}) {
 	})
 
 	c.podIndexer = podInformer.GetIndexer()
+	c.nodeLister = factory.Core().V1().Nodes().Lister()
 
-	nodeInformer.AddEventHandler(cacheResourceHandler(c.handleNode))
+	nodeInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    c.enqueueNode,
+		UpdateFunc: c.handleNodeUpdate,
+	})
 	podInformer.AddEventHandler(cacheResourceHandler(c.handlePod))
-	factory.Start(stop)
-	factory.WaitForCacheSync(stop)
+	factory.Start(ctx.Done())
+	defer factory.Shutdown()
+	for typ, ok := range factory.WaitForCacheSync(ctx.Done()) {
+		if !ok {
+			c.queue.ShutDown()
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("cache sync failed for %v", typ)
+		}
+	}
+	c.synced.Store(true)
+	if c.breaker != nil {
+		go c.runBreaker(ctx)
+	}
+
+	if c.leaderGate != nil {
+		select {
+		case <-c.leaderGate:
+		case <-ctx.Done():
+			c.queue.ShutDown()
+			return nil
+		}
+	}
 
 	// Optional backfill: add taint to new nodes that missed webhook (disabled by default)
 	if os.Getenv("STARTUP_BACKFILL") == "1" {
 		c.backfillTaint()
 	}
 
-	<-stop
+	var wg sync.WaitGroup
+	for i := 0; i < c.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for ctx.Err() == nil && c.processNextItem() {
+			}
+		}()
+	}
+
+	<-ctx.Done()
+	klog.Info("Controller stopping; waiting for in-flight reconciles")
+	c.queue.ShutDown()
+	wg.Wait()
+	return nil
+}
+
+func (c *Controller) enqueueNode(obj interface{}) {
+	n, ok := obj.(*corev1.Node)
+	if !ok {
+		return
+	}
+	if c.queue == nil {
+		c.handleNode(n)
+		return
+	}
+	c.queue.Add(n.Name)
+}
+
+// handleNodeUpdate notes a startup taint that vanished without a completion
+// record (someone else removed it, or the kubelet re-registered and reset taints)
+// so the next sync gates the node again.
+func (c *Controller) handleNodeUpdate(oldObj, newObj interface{}) {
+	oldNode, ok := oldObj.(*corev1.Node)
+	newNode, ok2 := newObj.(*corev1.Node)
+	if ok && ok2 && HasStartupTaint(oldNode) && !HasStartupTaint(newNode) && !completionRecorded(newNode) {
+		c.mu.Lock()
+		c.dropped[newNode.Name] = newNode.UID
+		c.mu.Unlock()
+	}
+	c.enqueueNode(newObj)
+}
+
+func (c *Controller) takeDropped(node *corev1.Node) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	uid, ok := c.dropped[node.Name]
+	delete(c.dropped, node.Name)
+	return ok && uid == node.UID
+}
+
+func (c *Controller) processNextItem() bool {
+	name, quit := c.queue.Get()
+	if quit {
+		return false
+	}
+	defer c.queue.Done(name)
+
+	node, err := c.nodeLister.Get(name)
+	if apierrors.IsNotFound(err) {
+		c.forgetRelease(name)
+		c.queue.Forget(name)
+		return true
+	}
+	if err == nil {
+		err = c.syncNode(node)
+	}
+	if err != nil {
+		klog.Warningf("sync node %s: %v", name, err)
+		c.queue.AddRateLimited(name)
+		return true
+	}
+	c.queue.Forget(name)
+	return true
 }
 
 func (c *Controller) handlePod(obj interface{}) {
@@ -68,7 +270,11 @@ func (c *Controller) handlePod(obj interface{}) {
 	if p.Spec.NodeName == "" {
 		return
 	}
-	// Re-evaluate node when startup pod condition changes
+	// Re-evaluate node when startup pod condition changes (via the queue once running)
+	if c.queue != nil {
+		c.queue.Add(p.Spec.NodeName)
+		return
+	}
 	n, err := c.client.CoreV1().Nodes().Get(context.TODO(), p.Spec.NodeName, metav1.GetOptions{})
 	if err != nil {
 		return
@@ -83,27 +289,62 @@ func (c *Controller) handleNode(obj interface{}) {
 	if !ok {
 		return
 	}
+	if err := c.syncNode(node); err != nil {
+		klog.Warningf("sync node %s: %v", node.Name, err)
+	}
+}
+
+// syncNode removes the startup taint once the node's init pod is ready, and
+// re-gates nodes that lost the taint before completing.
+func (c *Controller) syncNode(node *corev1.Node) error {
 	if !HasStartupTaint(node) {
-		return
+		return c.syncUntainted(node)
+	}
+	ov := NodeOverride(node)
+	if ov.Ungated() {
+		return c.releaseOverride(node, ov)
+	}
+	if err := c.recordInitFailures(node); err != nil {
+		return fmt.Errorf("record init failures: %w", err)
+	}
+	if err := c.syncStateLabel(node); err != nil {
+		return fmt.Errorf("update state label: %w", err)
 	}
-	ready, err := c.startupPodReady(node.Name)
+	ready, satisfied, err := c.checkReadiness(node)
 	if err != nil {
-		klog.Warningf("check startup pod on node %s: %v", node.Name, err)
-		return
+		return fmt.Errorf("check startup pod: %w", err)
 	}
 	if !ready {
-		return
+		c.forgetRelease(node.Name)
+		if KarpenterUnregistered(node) {
+			// Init pods would not tolerate Karpenter's NoExecute taint yet.
+			return nil
+		}
+		return c.ensureInitPod(node)
+	}
+	if ov == OverrideHold {
+		c.forgetRelease(node.Name)
+		c.noteOverride(node, ov, EventStartupHeld, "Ready but kept tainted by %s=true", HoldAnnotation)
+		return nil
 	}
-	if err := c.removeStartupTaint(node); err != nil {
-		klog.Warningf("remove startup taint from %s: %v", node.Name, err)
-	} else {
-		klog.Infof("Removed startup taint from node %s", node.Name)
+	if !c.admitRelease(node) {
+		return nil
 	}
+	satisfiedBy := fmt.Sprintf("policy %s", c.PolicyFor(node).Name)
+	if len(satisfied) > 0 {
+		satisfiedBy += ": " + strings.Join(satisfied, "; ")
+	}
+	if err := c.removeStartupTaint(node, satisfiedBy); err != nil {
+		return fmt.Errorf("remove startup taint: %w", err)
+	}
+	klog.Infof("Removed startup taint from node %s (%s)", node.Name, satisfiedBy)
+	c.cleanupInitPods(node)
+	return nil
 }
 
 func HasStartupTaint(node *corev1.Node) bool {
 	for _, t := range node.Spec.Taints {
-		if t.Key == TaintKey && t.Value == TaintValue && t.Effect == corev1.TaintEffectNoSchedule {
+		if isStartupTaint(t) {
 			return true
 		}
 	}
@@ -111,6 +352,72 @@ func HasStartupTaint(node *corev1.Node) bool {
 }
 
 func (c *Controller) startupPodReady(nodeName string) (bool, error) {
+	pods, err := c.startupPods(nodeName)
+	if err != nil {
+		return false, err
+	}
+	for _, p := range pods {
+		if ready, _ := podReady(p); ready {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// PendingComponents explains why a node is still gated (the reasons of its
+// policy's readiness check). Empty when the node's startup work is complete.
+// Safe to call from other goroutines (e.g. the webhook) once Run has synced.
+func (c *Controller) PendingComponents(node *corev1.Node) ([]string, error) {
+	if !c.HasSynced() {
+		return nil, errors.New("pod cache not synced")
+	}
+	ready, pending, err := c.evaluate(node)
+	if ready && c.releases != nil {
+		if pos, total := c.releases.position(node.Name); pos > 0 {
+			return []string{fmt.Sprintf("waiting for release slot (position %d of %d)", pos, total)}, nil
+		}
+	}
+	return pending, err
+}
+
+// Evaluate runs the node's readiness policy once, listing pods from the API.
+// For tools that never call Run (e.g. the kubectl plugin).
+func (c *Controller) Evaluate(node *corev1.Node) (bool, []string, error) {
+	if c.queue != nil {
+		return false, nil, errors.New("evaluate: controller is running; use PendingComponents")
+	}
+	return c.evaluate(node)
+}
+
+// evaluate runs the node's policy readiness check against its init pods and
+// returns what is still pending.
+func (c *Controller) evaluate(node *corev1.Node) (bool, []string, error) {
+	ready, reasons, err := c.checkReadiness(node)
+	if ready {
+		return true, nil, err
+	}
+	return false, reasons, err
+}
+
+// checkReadiness is evaluate, but when ready returns what satisfied the policy.
+func (c *Controller) checkReadiness(node *corev1.Node) (bool, []string, error) {
+	pods, err := c.startupPods(node.Name)
+	if err != nil {
+		return false, nil, err
+	}
+	if KarpenterUnregistered(node) {
+		return false, []string{fmt.Sprintf("waiting for Karpenter to register the node (%s taint)", KarpenterUnregisteredTaintKey)}, nil
+	}
+	if n := len(initFailures(node, pods)); n > c.initRetries {
+		return false, []string{fmt.Sprintf("init failed %d times (retry budget %d exhausted)", n, c.initRetries)}, nil
+	}
+	ready, reasons := c.readinessFor(c.PolicyFor(node)).Ready(ReadinessInput{Node: node, Pods: pods})
+	return ready, reasons, nil
+}
+
+// startupPods returns the init pods bound to nodeName.
+func (c *Controller) startupPods(nodeName string) ([]*corev1.Pod, error) {
+	var out []*corev1.Pod
 	// Use index (fall back to API list if indexer nil)
 	if c.podIndexer != nil {
 		objs, _ := c.podIndexer.ByIndex("byNode", nodeName)
@@ -119,26 +426,9 @@ func (c *Controller) startupPodReady(nodeName string) (bool, error) {
 			if p.Labels[StartPodLabelKey] != StartPodLabelValue {
 				continue
 			}
-			if p.Annotations != nil && p.Annotations[StartPodReadyAnnotation] == "true" {
-				return true, nil
-			}
-			allReady := true
-			for _, cs := range p.Status.ContainerStatuses {
-				if !cs.Ready {
-					allReady = false
-					break
-				}
-			}
-			if !allReady {
-				continue
-			}
-			for _, cond := range p.Status.Conditions {
-				if cond.Type == corev1.PodReady && cond.Status == corev1.ConditionTrue {
-					return true, nil
-				}
-			}
+			out = append(out, p)
 		}
-		return false, nil
+		return out, nil
 	}
 	// Fallback to API list
 	pods, err := c.client.CoreV1().Pods("").List(context.TODO(), metav1.ListOptions{
@@ -146,90 +436,174 @@ func (c *Controller) startupPodReady(nodeName string) (bool, error) {
 		LabelSelector: StartPodLabelKey + "=" + StartPodLabelValue,
 	})
 	if err != nil {
-		return false, err
+		return nil, err
 	}
-	for _, p := range pods.Items {
-		if p.Spec.NodeName != nodeName {
+	for i := range pods.Items {
+		if pods.Items[i].Spec.NodeName != nodeName {
 			continue
 		}
-		if p.Annotations != nil && p.Annotations[StartPodReadyAnnotation] == "true" {
-			return true, nil
-		}
-		allReady := true
-		readyCond := false
-		for _, cs := range p.Status.ContainerStatuses {
-			if !cs.Ready {
-				allReady = false
-				break
-			}
-		}
-		for _, cond := range p.Status.Conditions {
-			if cond.Type == corev1.PodReady && cond.Status == corev1.ConditionTrue {
-				readyCond = true
-				break
-			}
+		out = append(out, &pods.Items[i])
+	}
+	return out, nil
+}
+
+// podReady: annotation shortcut, a run-to-completion pod that Succeeded, or all
+// containers Ready plus the PodReady condition. The reason describes what is
+// missing when not ready.
+func podReady(p *corev1.Pod) (bool, string) {
+	if p.Annotations != nil && p.Annotations[StartPodReadyAnnotation] == "true" {
+		return true, "annotation " + StartPodReadyAnnotation + "=true"
+	}
+	switch p.Status.Phase {
+	case corev1.PodSucceeded:
+		return true, "phase Succeeded"
+	case corev1.PodFailed:
+		return false, failureReason(p)
+	}
+	for _, cs := range p.Status.ContainerStatuses {
+		if !cs.Ready {
+			return false, fmt.Sprintf("container %s not ready", cs.Name)
 		}
-		if allReady && readyCond {
-			return true, nil
+	}
+	for _, cond := range p.Status.Conditions {
+		if cond.Type == corev1.PodReady && cond.Status == corev1.ConditionTrue {
+			return true, "Ready"
 		}
 	}
-	return false, nil
+	return false, "PodReady condition not true"
 }
 
-func (c *Controller) removeStartupTaint(node *corev1.Node) error {
+// removeStartupTaint releases the node, recording satisfiedBy (what made it ready).
+func (c *Controller) removeStartupTaint(node *corev1.Node, satisfiedBy string) error {
 	return retry.RetryOnConflict(retry.DefaultBackoff, func() error {
 		n, err := c.client.CoreV1().Nodes().Get(context.TODO(), node.Name, metav1.GetOptions{})
 		if err != nil {
 			return err
 		}
-		newTaints := n.Spec.Taints[:0]
-		changed := false
-		for _, t := range n.Spec.Taints {
-			if t.Key == TaintKey && t.Value == TaintValue && t.Effect == corev1.TaintEffectNoSchedule {
-				changed = true
-				continue
-			}
-			newTaints = append(newTaints, t)
+		if !releaseNode(n) {
+			return nil
 		}
-		if !changed {
+		if satisfiedBy != "" {
+			n.Annotations[NodeSatisfiedByAnnotation] = satisfiedBy
+		}
+		if _, err = c.client.CoreV1().Nodes().Update(context.TODO(), n, metav1.UpdateOptions{}); err == nil {
+			c.metrics.releases.WithLabelValues(releasedReady).Inc()
+		}
+		return err
+	})
+}
+
+func (c *Controller) syncUntainted(node *corev1.Node) error {
+	c.forgetRelease(node.Name)
+	if ov := NodeOverride(node); ov.Ungated() {
+		// Webhook skipped it at creation (or the taint is already gone): never re-gate.
+		c.takeDropped(node)
+		if !completionRecorded(node) {
+			c.noteOverride(node, ov, EventStartupSkipped, "Not gated: %s=true", ov.Annotation())
+		}
+		return nil
+	}
+	switch {
+	case c.takeDropped(node):
+		// Possibly an operator's deliberate `kubectl taint ... -`; re-gating a busy
+		// node would block (or, with NoExecute, evict) what already runs there.
+		if c.hasWorkloadPods(node.Name) {
+			klog.Infof("Startup taint dropped from node %s, which already runs workloads; not re-gating", node.Name)
 			return nil
 		}
-		n.Spec.Taints = newTaints
-		if n.Annotations == nil {
-			n.Annotations = map[string]string{}
+		if ok, reason := c.AllowTaint(node); !ok {
+			klog.Infof("Not re-gating node %s: %s", node.Name, reason)
+			return nil
+		}
+		return c.regate(node, "startup taint removed before init completed")
+	case CompletionStale(node):
+		// Re-created Node carrying an old completion; treat like backfill and leave busy nodes alone.
+		if c.hasWorkloadPods(node.Name) {
+			klog.Infof("Node %s re-registered with stale completion but already runs workloads; not re-gating", node.Name)
+			return nil
+		}
+		if ok, reason := c.AllowTaint(node); !ok {
+			klog.Infof("Not re-gating node %s: %s", node.Name, reason)
+			return nil
+		}
+		return c.regate(node, "node re-registered with stale completion annotation")
+	case completionRecorded(node):
+		c.cleanupInitPods(node)
+	}
+	return nil
+}
+
+// regate puts the startup taint back and drops completion annotations that no longer apply.
+func (c *Controller) regate(node *corev1.Node, reason string) error {
+	err := retry.RetryOnConflict(retry.DefaultBackoff, func() error {
+		n, err := c.client.CoreV1().Nodes().Get(context.TODO(), node.Name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		if n.UID != node.UID || !gateNode(n, c.PolicyFor(n)) {
+			return nil
 		}
-		n.Annotations[NodeStartupCompletedAnnotation] = strconv.FormatInt(time.Now().Unix(), 10)
 		_, err = c.client.CoreV1().Nodes().Update(context.TODO(), n, metav1.UpdateOptions{})
 		return err
 	})
+	if err != nil {
+		return fmt.Errorf("re-gate: %w", err)
+	}
+	klog.Infof("Re-applied startup taint to node %s: %s", node.Name, reason)
+	return nil
 }
 
 func (c *Controller) backfillTaint() {
-	nodes, err := c.client.CoreV1().Nodes().List(context.TODO(), metav1.ListOptions{})
-	if err != nil {
+	if _, err := c.Backfill(context.TODO(), false); err != nil {
 		klog.Warningf("backfill list nodes: %v", err)
-		return
 	}
+}
+
+// Backfill taints nodes that missed the webhook: untainted, never completed, and
+// running no workload pods. With dryRun it only reports which nodes it would touch.
+func (c *Controller) Backfill(ctx context.Context, dryRun bool) ([]string, error) {
+	nodes, err := c.client.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	var touched []string
 	for i := range nodes.Items {
 		n := &nodes.Items[i]
 		if HasStartupTaint(n) {
 			continue
 		}
-		// Skip nodes already marked completed
+		// Skip nodes already marked completed or opted out
 		if n.Annotations != nil && n.Annotations[NodeStartupCompletedAnnotation] != "" {
 			continue
 		}
+		if NodeOverride(n).Ungated() {
+			continue
+		}
+		if ok, _ := c.AllowTaint(n); !ok {
+			continue
+		}
 		// Add taint only if no non-system pods running (avoid disrupting established workloads)
 		if c.hasWorkloadPods(n.Name) {
 			continue
 		}
-		n.Spec.Taints = append(n.Spec.Taints, StartupTaint)
-		if _, err := c.client.CoreV1().Nodes().Update(context.TODO(), n, metav1.UpdateOptions{}); err != nil {
+		if dryRun {
+			touched = append(touched, n.Name)
+			continue
+		}
+		gateNode(n, c.PolicyFor(n))
+		if _, err := c.client.CoreV1().Nodes().Update(ctx, n, metav1.UpdateOptions{}); err != nil {
 			klog.Warningf("backfill add taint %s: %v", n.Name, err)
 		} else {
 			klog.Infof("Backfilled startup taint on node %s", n.Name)
+			touched = append(touched, n.Name)
 		}
 	}
+	return touched, nil
+}
+
+// isInitPod reports an init pod (StartPodLabelKey=StartPodLabelValue); it is not a workload.
+func isInitPod(p *corev1.Pod) bool {
+	return p.Labels[StartPodLabelKey] == StartPodLabelValue
 }
 
 func (c *Controller) hasWorkloadPods(nodeName string) bool {
@@ -238,7 +612,7 @@ func (c *Controller) hasWorkloadPods(nodeName string) bool {
 		for _, o := range objs {
 			p := o.(*corev1.Pod)
 			ns := p.Namespace
-			if ns != "kube-system" && ns != "kube-public" {
+			if ns != "kube-system" && ns != "kube-public" && !isInitPod(p) {
 				return true
 			}
 		}
@@ -252,7 +626,10 @@ func (c *Controller) hasWorkloadPods(nodeName string) bool {
 		return true
 	}
 	for _, p := range pods.Items {
-		if p.Namespace != "kube-system" && p.Namespace != "kube-public" {
+		if p.Spec.NodeName != nodeName {
+			continue
+		}
+		if p.Namespace != "kube-system" && p.Namespace != "kube-public" && !isInitPod(&p) {
 			return true
 		}
 	}
@@ -34,7 +34,7 @@ func TestRemoveStartupTaint_Idempotent_NoSecondUpdate(t *testing.T) {
 	})
 
 	// First removal -> expect update
-	if err := c.removeStartupTaint(n); err != nil {
+	if err := c.removeStartupTaint(n, ""); err != nil {
 		t.Fatalf("first remove err: %v", err)
 	}
 	if atomic.LoadInt32(&updates) == 0 {
@@ -43,7 +43,7 @@ func TestRemoveStartupTaint_Idempotent_NoSecondUpdate(t *testing.T) {
 	firstCount := atomic.LoadInt32(&updates)
 
 	// Second removal -> no change, so no new update
-	if err := c.removeStartupTaint(n); err != nil {
+	if err := c.removeStartupTaint(n, ""); err != nil {
 		t.Fatalf("second remove err: %v", err)
 	}
 	if atomic.LoadInt32(&updates) != firstCount {
@@ -69,7 +69,7 @@ func TestRemoveStartupTaint_RetryOnConflict(t *testing.T) {
 		return false, nil, nil
 	})
 
-	if err := c.removeStartupTaint(n); err != nil {
+	if err := c.removeStartupTaint(n, ""); err != nil {
 		t.Fatalf("expected success after retry, got err: %v", err)
 	}
 	if attempts < 2 {
@@ -92,7 +92,7 @@ func TestRemoveStartupTaint_NoTaint_NoAnnotationMutation(t *testing.T) {
 	orig := n.Annotations[NodeStartupCompletedAnnotation]
 
 	c, _ := newControllerWith(n)
-	if err := c.removeStartupTaint(n); err != nil {
+	if err := c.removeStartupTaint(n, ""); err != nil {
 		t.Fatalf("remove err: %v", err)
 	}
 	if n.Annotations[NodeStartupCompletedAnnotation] != orig {
@@ -259,7 +259,7 @@ func BenchmarkRemoveStartupTaintDeepSlice(b *testing.B) {
 			n.Spec.Taints = append(n.Spec.Taints, StartupTaint)
 			_, _ = client.CoreV1().Nodes().Update(context.TODO(), n, metav1.UpdateOptions{})
 		}
-		if err := c.removeStartupTaint(n); err != nil {
+		if err := c.removeStartupTaint(n, ""); err != nil {
 			b.Fatalf("remove err: %v", err)
 		}
 	}
@@ -244,7 +244,7 @@ func TestHasStartupTaint(t *testing.T) {
 func TestRemoveStartupTaint(t *testing.T) {
 	n := makeNode("n1", StartupTaint)
 	c, client := newControllerWith(n)
-	if err := c.removeStartupTaint(n); err != nil {
+	if err := c.removeStartupTaint(n, ""); err != nil {
 		t.Fatalf("remove err: %v", err)
 	}
 	got, _ := client.CoreV1().Nodes().Get(ctx(), "n1", metav1.GetOptions{})
@@ -306,6 +306,34 @@ func TestHandlePod_TrigersRemovalOnReadyTransition(t *testing.T) {
 	}
 }
 
+func TestPendingComponents(t *testing.T) {
+	notReady := podWith("init-a", "n1", labeledStartup(), nil,
+		[]corev1.ContainerStatus{{Name: "warm", Ready: false}}, nil)
+	c, _ := newController(notReady)
+	n := makeNode("n1", StartupTaint)
+	if _, err := c.PendingComponents(n); err == nil {
+		t.Fatalf("expected error before caches synced")
+	}
+	_, pending, err := c.evaluate(n)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(pending) != 1 || pending[0] != "default/init-a: container warm not ready" {
+		t.Fatalf("unexpected pending %v", pending)
+	}
+
+	none, _ := newController()
+	if _, pending, _ := none.evaluate(n); len(pending) != 1 {
+		t.Fatalf("expected a 'no init pod' entry, got %v", pending)
+	}
+
+	ready := podWith("init-b", "n1", labeledStartup(), map[string]string{StartPodReadyAnnotation: "true"}, nil, nil)
+	done, _ := newController(notReady, ready)
+	if _, pending, _ := done.evaluate(n); len(pending) != 0 {
+		t.Fatalf("expected nothing pending once a pod is ready, got %v", pending)
+	}
+}
+
 func TestBackfillTaint_AddsWhenEligible(t *testing.T) {
 	n := makeNode("n1") // no taint
 	c, client := newControllerWith(n)
@@ -352,6 +380,48 @@ func TestHasWorkloadPods(t *testing.T) {
 	}
 }
 
+func TestRun_RemovesTaintViaQueueAndStopsOnCancel(t *testing.T) {
+	n := makeNode("n1", StartupTaint)
+	p := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "init-n1",
+			Namespace:   "default",
+			Labels:      map[string]string{StartPodLabelKey: StartPodLabelValue},
+			Annotations: map[string]string{StartPodReadyAnnotation: "true"},
+		},
+		Spec: corev1.PodSpec{NodeName: "n1"},
+	}
+	c, client := newControllerWith(n, p)
+	runCtx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- c.Run(runCtx) }()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		got, _ := client.CoreV1().Nodes().Get(ctx(), "n1", metav1.GetOptions{})
+		if !HasStartupTaint(got) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("taint not removed by workers")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if !c.HasSynced() {
+		t.Fatalf("expected HasSynced after caches synced")
+	}
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("run: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Run did not return after cancel")
+	}
+}
+
 // helper context
 func ctx() context.Context {
 	return context.TODO()
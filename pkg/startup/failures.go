@@ -0,0 +1,150 @@
+package startup
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/util/retry"
+	"k8s.io/klog/v2"
+)
+
+// Event reasons emitted on Nodes.
+const (
+	EventInitPodFailed        = "InitPodFailed"
+	EventInitRetriesExhausted = "InitRetriesExhausted"
+)
+
+// initFailures returns the failed init pod UIDs counted for the node: those
+// already recorded on it plus any currently visible in phase Failed. Recording
+// UIDs keeps the count stable when failed pods are garbage collected or seen twice.
+func initFailures(node *corev1.Node, pods []*corev1.Pod) sets.Set[string] {
+	out := recordedFailures(node)
+	for _, p := range pods {
+		if p.Status.Phase == corev1.PodFailed {
+			out.Insert(string(p.UID))
+		}
+	}
+	return out
+}
+
+func recordedFailures(node *corev1.Node) sets.Set[string] {
+	out := sets.New[string]()
+	for _, uid := range strings.Split(node.Annotations[NodeInitFailuresAnnotation], ",") {
+		if uid = strings.TrimSpace(uid); uid != "" {
+			out.Insert(uid)
+		}
+	}
+	return out
+}
+
+// InitFailureCount reports how many failed init pods are recorded on the node.
+func InitFailureCount(node *corev1.Node) int {
+	return recordedFailures(node).Len()
+}
+
+// recordInitFailures persists newly failed init pods on the node and emits
+// events. The owning Job / DaemonSet retries; once more than initRetries pods
+// have failed the node stays tainted until an operator intervenes.
+func (c *Controller) recordInitFailures(node *corev1.Node) error {
+	pods, err := c.startupPods(node.Name)
+	if err != nil {
+		return err
+	}
+	recorded := recordedFailures(node)
+	var fresh []*corev1.Pod
+	for _, p := range pods {
+		if p.Status.Phase == corev1.PodFailed && !recorded.Has(string(p.UID)) {
+			fresh = append(fresh, p)
+		}
+	}
+	if len(fresh) == 0 {
+		return nil
+	}
+	all := initFailures(node, pods)
+	err = retry.RetryOnConflict(retry.DefaultBackoff, func() error {
+		n, err := c.client.CoreV1().Nodes().Get(context.TODO(), node.Name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		if n.UID != node.UID {
+			return nil
+		}
+		merged := recordedFailures(n).Union(all)
+		if n.Annotations == nil {
+			n.Annotations = map[string]string{}
+		}
+		n.Annotations[NodeInitFailuresAnnotation] = strings.Join(sets.List(merged), ",")
+		if merged.Len() > c.initRetries {
+			setStateLabel(n, StateFailed)
+		}
+		_, err = c.client.CoreV1().Nodes().Update(context.TODO(), n, metav1.UpdateOptions{})
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	count := all.Len()
+	for _, p := range fresh {
+		klog.Warningf("Init pod %s/%s on node %s failed (%d/%d): %s", p.Namespace, p.Name, node.Name, count, c.initRetries+1, failureReason(p))
+		c.eventf(node, corev1.EventTypeWarning, EventInitPodFailed, "Init pod %s/%s failed (%d/%d): %s", p.Namespace, p.Name, count, c.initRetries+1, failureReason(p))
+	}
+	if before := recorded.Len(); before <= c.initRetries && count > c.initRetries {
+		klog.Errorf("Node %s: init failed %d times; keeping startup taint", node.Name, count)
+		c.eventf(node, corev1.EventTypeWarning, EventInitRetriesExhausted, "Init failed %d times; node stays tainted (remove annotation %s to retry)", count, NodeInitFailuresAnnotation)
+	}
+	return nil
+}
+
+// syncStateLabel keeps a gated node's state label in line with its retry budget
+// (e.g. back to gating once an operator clears the failures to retry, or set on
+// nodes gated before the label existed).
+func (c *Controller) syncStateLabel(node *corev1.Node) error {
+	if node.Labels[StateLabel] == c.gatedState(node) {
+		return nil
+	}
+	return retry.RetryOnConflict(retry.DefaultBackoff, func() error {
+		n, err := c.client.CoreV1().Nodes().Get(context.TODO(), node.Name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		want := c.gatedState(n)
+		if n.UID != node.UID || !HasStartupTaint(n) || n.Labels[StateLabel] == want {
+			return nil
+		}
+		setStateLabel(n, want)
+		_, err = c.client.CoreV1().Nodes().Update(context.TODO(), n, metav1.UpdateOptions{})
+		return err
+	})
+}
+
+func (c *Controller) gatedState(n *corev1.Node) string {
+	if recordedFailures(n).Len() > c.initRetries {
+		return StateFailed
+	}
+	return StateGating
+}
+
+// failureReason summarises why a pod failed: the first non-zero container exit, else the pod reason.
+func failureReason(p *corev1.Pod) string {
+	for _, cs := range append(append([]corev1.ContainerStatus{}, p.Status.InitContainerStatuses...), p.Status.ContainerStatuses...) {
+		if t := cs.State.Terminated; t != nil && t.ExitCode != 0 {
+			return fmt.Sprintf("container %s exited %d", cs.Name, t.ExitCode)
+		}
+	}
+	if p.Status.Reason != "" {
+		return "failed: " + p.Status.Reason
+	}
+	return "failed"
+}
+
+func (c *Controller) eventf(node *corev1.Node, eventType, reason, format string, args ...interface{}) {
+	if c.recorder == nil {
+		return
+	}
+	c.recorder.Eventf(node, eventType, reason, format, args...)
+}
@@ -0,0 +1,149 @@
+package startup
+
+import (
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+)
+
+func initPod(name string, uid types.UID, phase corev1.PodPhase, exitCode int32) *corev1.Pod {
+	p := podWith(name, "n1", labeledStartup(), nil, []corev1.ContainerStatus{{
+		Name:  "warmup",
+		State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{ExitCode: exitCode}},
+	}}, nil)
+	p.UID = uid
+	p.Status.Phase = phase
+	return p
+}
+
+func TestSyncNode_SucceededInitPodReleasesNode(t *testing.T) {
+	n := makeNode("n1", StartupTaint)
+	c, client := newControllerWith(n, initPod("warmup-abc", "u1", corev1.PodSucceeded, 0))
+	if err := c.syncNode(n); err != nil {
+		t.Fatalf("sync: %v", err)
+	}
+	got, _ := client.CoreV1().Nodes().Get(ctx(), "n1", metav1.GetOptions{})
+	if HasStartupTaint(got) {
+		t.Fatalf("succeeded init pod should release node")
+	}
+}
+
+func TestSyncNode_FailedInitPodsSpendRetryBudget(t *testing.T) {
+	n := makeNode("n1", StartupTaint)
+	n.UID = "node-uid"
+	c, client := newControllerWith(n,
+		initPod("warmup-1", "u1", corev1.PodFailed, 1),
+		initPod("warmup-2", "u2", corev1.PodFailed, 2),
+	)
+	rec := record.NewFakeRecorder(10)
+	WithRecorder(rec)(c)
+	WithInitRetries(2)(c)
+
+	if err := c.syncNode(n); err != nil {
+		t.Fatalf("sync: %v", err)
+	}
+	got, _ := client.CoreV1().Nodes().Get(ctx(), "n1", metav1.GetOptions{})
+	if v := got.Annotations[NodeInitFailuresAnnotation]; v != "u1,u2" {
+		t.Fatalf("failures annotation=%q", v)
+	}
+	if len(rec.Events) != 2 {
+		t.Fatalf("expected 2 failure events, got %d", len(rec.Events))
+	}
+	if e := <-rec.Events; !strings.Contains(e, EventInitPodFailed) || !strings.Contains(e, "exited 1") {
+		t.Fatalf("unexpected event %q", e)
+	}
+	<-rec.Events
+
+	// Same pods seen again: nothing new recorded.
+	if err := c.syncNode(got); err != nil {
+		t.Fatalf("resync: %v", err)
+	}
+	if len(rec.Events) != 0 {
+		t.Fatalf("expected no new events on resync")
+	}
+
+	// Third failure exhausts the budget; a later success does not release the node.
+	_, _ = client.CoreV1().Pods("default").Create(ctx(), initPod("warmup-3", "u3", corev1.PodFailed, 1), metav1.CreateOptions{})
+	_, _ = client.CoreV1().Pods("default").Create(ctx(), initPod("warmup-4", "u4", corev1.PodSucceeded, 0), metav1.CreateOptions{})
+	if err := c.syncNode(got); err != nil {
+		t.Fatalf("sync: %v", err)
+	}
+	got, _ = client.CoreV1().Nodes().Get(ctx(), "n1", metav1.GetOptions{})
+	if !HasStartupTaint(got) {
+		t.Fatalf("node with exhausted budget must stay tainted")
+	}
+	if InitFailureCount(got) != 3 {
+		t.Fatalf("failure count=%d", InitFailureCount(got))
+	}
+	var exhausted bool
+	for len(rec.Events) > 0 {
+		if strings.Contains(<-rec.Events, EventInitRetriesExhausted) {
+			exhausted = true
+		}
+	}
+	if !exhausted {
+		t.Fatalf("expected %s event", EventInitRetriesExhausted)
+	}
+	if _, pending, _ := c.evaluate(got); len(pending) != 1 || !strings.Contains(pending[0], "retry budget 2 exhausted") {
+		t.Fatalf("unexpected pending %v", pending)
+	}
+}
+
+func TestRegate_ClearsInitFailures(t *testing.T) {
+	n := makeNode("n1")
+	n.UID = "new"
+	n.Annotations = map[string]string{NodeInitFailuresAnnotation: "u1", ComponentReadyAnnotation("init"): "2024-01-01T00:00:00Z"}
+	c, client := newControllerWith(n)
+	if err := c.regate(n, "test"); err != nil {
+		t.Fatalf("regate: %v", err)
+	}
+	got, _ := client.CoreV1().Nodes().Get(ctx(), "n1", metav1.GetOptions{})
+	if _, ok := got.Annotations[NodeInitFailuresAnnotation]; ok {
+		t.Fatalf("failures annotation should be cleared on re-gate")
+	}
+	if _, ok := got.Annotations[ComponentReadyAnnotation("init")]; ok {
+		t.Fatalf("component reports should be cleared on re-gate")
+	}
+}
+
+func TestSyncNode_StateLabel(t *testing.T) {
+	n := makeNode("n1", StartupTaint)
+	n.UID = "node-uid"
+	c, client := newControllerWith(n, initPod("warmup-1", "u1", corev1.PodFailed, 1))
+	WithInitRetries(0)(c)
+	state := func() string {
+		got, _ := client.CoreV1().Nodes().Get(ctx(), "n1", metav1.GetOptions{})
+		return got.Labels[StateLabel]
+	}
+
+	// Budget of 0: the first failure marks the node failed.
+	if err := c.syncNode(n); err != nil {
+		t.Fatal(err)
+	}
+	if s := state(); s != StateFailed {
+		t.Fatalf("state=%q, want %s", s, StateFailed)
+	}
+
+	// Operator clears the failures to retry: back to gating.
+	got, _ := client.CoreV1().Nodes().Get(ctx(), "n1", metav1.GetOptions{})
+	delete(got.Annotations, NodeInitFailuresAnnotation)
+	got, _ = client.CoreV1().Nodes().Update(ctx(), got, metav1.UpdateOptions{})
+	if err := c.syncStateLabel(got); err != nil {
+		t.Fatal(err)
+	}
+	if s := state(); s != StateGating {
+		t.Fatalf("state=%q, want %s", s, StateGating)
+	}
+
+	got, _ = client.CoreV1().Nodes().Get(ctx(), "n1", metav1.GetOptions{})
+	if err := c.removeStartupTaint(got, ""); err != nil {
+		t.Fatal(err)
+	}
+	if s := state(); s != StateReady {
+		t.Fatalf("state=%q, want %s", s, StateReady)
+	}
+}
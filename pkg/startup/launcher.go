@@ -0,0 +1,211 @@
+package startup
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog/v2"
+)
+
+// Event reasons for controller-launched init pods.
+const (
+	EventInitPodCreated = "InitPodCreated"
+)
+
+const (
+	initBackoffBase = 10 * time.Second
+	initBackoffMax  = 5 * time.Minute
+)
+
+// WithInitPodTemplate makes the controller launch a node-pinned init pod from the
+// PodTemplate ref for tainted nodes whose policy does not name its own.
+func WithInitPodTemplate(ref types.NamespacedName) Option {
+	return func(c *Controller) {
+		c.initPodTemplate = &ref
+	}
+}
+
+func (c *Controller) templateFor(node *corev1.Node) *types.NamespacedName {
+	if p := c.PolicyFor(node); p.InitPodTemplate != nil {
+		return p.InitPodTemplate
+	}
+	return c.initPodTemplate
+}
+
+// ensureInitPod launches an init pod for a gated node when a template is
+// configured and no attempt is in flight. Failed attempts are replaced after an
+// exponential backoff until the retry budget is spent.
+func (c *Controller) ensureInitPod(node *corev1.Node) error {
+	ref := c.templateFor(node)
+	if ref == nil {
+		return nil
+	}
+	pods, err := c.startupPods(node.Name)
+	if err != nil {
+		return err
+	}
+	var failed []*corev1.Pod
+	var lastFailure time.Time
+	for _, p := range pods {
+		switch p.Status.Phase {
+		case corev1.PodFailed:
+			if metav1.IsControlledBy(p, node) {
+				failed = append(failed, p)
+				if t := finishedAt(p); t.After(lastFailure) {
+					lastFailure = t
+				}
+			}
+		default:
+			// Running, pending or succeeded: the readiness policy decides.
+			return nil
+		}
+	}
+
+	// The cached node may lag behind the failure count just recorded.
+	fresh, err := c.client.CoreV1().Nodes().Get(context.TODO(), node.Name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	if fresh.UID != node.UID {
+		return nil
+	}
+	failures := InitFailureCount(fresh)
+	if failures > c.initRetries {
+		return nil
+	}
+	if len(failed) > 0 {
+		if wait := time.Until(lastFailure.Add(initBackoff(failures))); wait > 0 {
+			if c.queue != nil {
+				c.queue.AddAfter(node.Name, wait)
+			}
+			return nil
+		}
+		for _, p := range failed {
+			if err := c.deletePod(p); err != nil {
+				return err
+			}
+		}
+	}
+
+	tmpl, err := c.client.CoreV1().PodTemplates(ref.Namespace).Get(context.TODO(), ref.Name, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("get init pod template %s: %w", ref, err)
+	}
+	pod := newInitPod(tmpl, fresh, c.TaintFor(fresh), failures+1)
+	if _, err := c.client.CoreV1().Pods(pod.Namespace).Create(context.TODO(), pod, metav1.CreateOptions{}); err != nil {
+		if apierrors.IsAlreadyExists(err) {
+			return nil
+		}
+		return fmt.Errorf("create init pod: %w", err)
+	}
+	klog.Infof("Launched init pod %s/%s on node %s (attempt %d)", pod.Namespace, pod.Name, node.Name, failures+1)
+	c.eventf(node, corev1.EventTypeNormal, EventInitPodCreated, "Created init pod %s/%s from template %s (attempt %d)", pod.Namespace, pod.Name, ref, failures+1)
+	return nil
+}
+
+// cleanupInitPods deletes the init pods the controller launched for a node once it is released.
+func (c *Controller) cleanupInitPods(node *corev1.Node) {
+	pods, err := c.startupPods(node.Name)
+	if err != nil {
+		return
+	}
+	for _, p := range pods {
+		if !metav1.IsControlledBy(p, node) || p.DeletionTimestamp != nil {
+			continue
+		}
+		if err := c.deletePod(p); err != nil {
+			klog.Warningf("clean up init pod %s/%s: %v", p.Namespace, p.Name, err)
+		}
+	}
+}
+
+func (c *Controller) deletePod(p *corev1.Pod) error {
+	err := c.client.CoreV1().Pods(p.Namespace).Delete(context.TODO(), p.Name, metav1.DeleteOptions{
+		Preconditions: metav1.NewUIDPreconditions(string(p.UID)),
+	})
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+// newInitPod builds attempt n of a node's init pod: pinned with nodeName,
+// tolerating the startup taint, and owned by the Node so it is garbage collected
+// with it.
+func newInitPod(tmpl *corev1.PodTemplate, node *corev1.Node, taint corev1.Taint, attempt int) *corev1.Pod {
+	meta := tmpl.Template.ObjectMeta.DeepCopy()
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        initPodName(node.Name, attempt),
+			Namespace:   tmpl.Namespace,
+			Labels:      meta.Labels,
+			Annotations: meta.Annotations,
+			OwnerReferences: []metav1.OwnerReference{
+				*metav1.NewControllerRef(node, corev1.SchemeGroupVersion.WithKind("Node")),
+			},
+		},
+		Spec: *tmpl.Template.Spec.DeepCopy(),
+	}
+	if pod.Labels == nil {
+		pod.Labels = map[string]string{}
+	}
+	pod.Labels[StartPodLabelKey] = StartPodLabelValue
+	pod.Spec.NodeName = node.Name
+	if pod.Spec.RestartPolicy == "" {
+		pod.Spec.RestartPolicy = corev1.RestartPolicyNever
+	}
+	tolerated := false
+	for _, t := range pod.Spec.Tolerations {
+		if t.ToleratesTaint(&taint) {
+			tolerated = true
+			break
+		}
+	}
+	if !tolerated {
+		pod.Spec.Tolerations = append(pod.Spec.Tolerations, corev1.Toleration{
+			Key:      taint.Key,
+			Operator: corev1.TolerationOpExists,
+			Effect:   taint.Effect,
+		})
+	}
+	return pod
+}
+
+// initPodName is deterministic per attempt so a duplicate create (stale cache) fails with AlreadyExists.
+func initPodName(nodeName string, attempt int) string {
+	suffix := fmt.Sprintf("-startup-%d", attempt)
+	if max := 253 - len(suffix); len(nodeName) > max {
+		nodeName = nodeName[:max]
+	}
+	return nodeName + suffix
+}
+
+func initBackoff(failures int) time.Duration {
+	if failures <= 0 {
+		return 0
+	}
+	d := initBackoffBase
+	for i := 1; i < failures && d < initBackoffMax; i++ {
+		d *= 2
+	}
+	if d > initBackoffMax {
+		d = initBackoffMax
+	}
+	return d
+}
+
+// finishedAt is when the pod's last container terminated (creation time if unknown).
+func finishedAt(p *corev1.Pod) time.Time {
+	t := p.CreationTimestamp.Time
+	for _, cs := range append(append([]corev1.ContainerStatus{}, p.Status.InitContainerStatuses...), p.Status.ContainerStatuses...) {
+		if term := cs.State.Terminated; term != nil && term.FinishedAt.After(t) {
+			t = term.FinishedAt.Time
+		}
+	}
+	return t
+}
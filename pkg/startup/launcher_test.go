@@ -0,0 +1,156 @@
+package startup
+
+import (
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+var testTemplateRef = types.NamespacedName{Namespace: "kube-system", Name: "warmup"}
+
+func testTemplate() *corev1.PodTemplate {
+	return &corev1.PodTemplate{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "kube-system", Name: "warmup"},
+		Template: corev1.PodTemplateSpec{
+			ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": "warmup"}},
+			Spec: corev1.PodSpec{
+				Containers: []corev1.Container{{Name: "warmup", Image: "busybox"}},
+			},
+		},
+	}
+}
+
+func launcherNode() *corev1.Node {
+	n := makeNode("n1", StartupTaint)
+	n.UID = "node-uid"
+	return n
+}
+
+func listInitPods(t *testing.T, client *fake.Clientset) []corev1.Pod {
+	t.Helper()
+	pods, err := client.CoreV1().Pods("kube-system").List(ctx(), metav1.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pods.Items
+}
+
+func TestEnsureInitPod_LaunchesPinnedPod(t *testing.T) {
+	n := launcherNode()
+	c, client := newControllerWith(n, testTemplate())
+	WithInitPodTemplate(testTemplateRef)(c)
+
+	if err := c.syncNode(n); err != nil {
+		t.Fatalf("sync: %v", err)
+	}
+	pods := listInitPods(t, client)
+	if len(pods) != 1 {
+		t.Fatalf("expected 1 init pod, got %d", len(pods))
+	}
+	p := pods[0]
+	if p.Name != "n1-startup-1" || p.Spec.NodeName != "n1" || p.Spec.RestartPolicy != corev1.RestartPolicyNever {
+		t.Fatalf("unexpected pod %s node=%s restart=%s", p.Name, p.Spec.NodeName, p.Spec.RestartPolicy)
+	}
+	if p.Labels[StartPodLabelKey] != StartPodLabelValue || p.Labels["app"] != "warmup" {
+		t.Fatalf("unexpected labels %v", p.Labels)
+	}
+	if !metav1.IsControlledBy(&p, n) {
+		t.Fatalf("pod not owned by node: %v", p.OwnerReferences)
+	}
+	tolerated := false
+	for _, tol := range p.Spec.Tolerations {
+		tolerated = tolerated || tol.ToleratesTaint(&StartupTaint)
+	}
+	if !tolerated {
+		t.Fatalf("pod does not tolerate startup taint")
+	}
+
+	// In flight: no second pod.
+	if err := c.syncNode(n); err != nil {
+		t.Fatalf("resync: %v", err)
+	}
+	if got := len(listInitPods(t, client)); got != 1 {
+		t.Fatalf("expected still 1 pod, got %d", got)
+	}
+}
+
+func TestEnsureInitPod_NoTemplateNoop(t *testing.T) {
+	n := launcherNode()
+	c, client := newControllerWith(n, testTemplate())
+	if err := c.syncNode(n); err != nil {
+		t.Fatalf("sync: %v", err)
+	}
+	if got := len(listInitPods(t, client)); got != 0 {
+		t.Fatalf("expected no pods without template, got %d", got)
+	}
+}
+
+func TestEnsureInitPod_RetriesFailedAttemptAfterBackoff(t *testing.T) {
+	n := launcherNode()
+	failed := newInitPod(testTemplate(), n, StartupTaint, 1)
+	failed.UID = "p1"
+	failed.Status.Phase = corev1.PodFailed
+	finished := time.Now()
+	failed.Status.ContainerStatuses = []corev1.ContainerStatus{{
+		Name:  "warmup",
+		State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{ExitCode: 1, FinishedAt: metav1.NewTime(finished)}},
+	}}
+	c, client := newControllerWith(n, testTemplate(), failed)
+	WithInitPodTemplate(testTemplateRef)(c)
+
+	// Within backoff: failure recorded, failed pod kept, nothing launched.
+	if err := c.syncNode(n); err != nil {
+		t.Fatalf("sync: %v", err)
+	}
+	pods := listInitPods(t, client)
+	if len(pods) != 1 || pods[0].Name != "n1-startup-1" {
+		t.Fatalf("expected only failed attempt during backoff, got %v", pods)
+	}
+
+	// Backoff elapsed: failed pod replaced by attempt 2.
+	failed.Status.ContainerStatuses[0].State.Terminated.FinishedAt = metav1.NewTime(finished.Add(-time.Minute))
+	if _, err := client.CoreV1().Pods("kube-system").UpdateStatus(ctx(), failed, metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.syncNode(n); err != nil {
+		t.Fatalf("sync: %v", err)
+	}
+	pods = listInitPods(t, client)
+	if len(pods) != 1 || pods[0].Name != "n1-startup-2" {
+		t.Fatalf("expected attempt 2 only, got %v", pods)
+	}
+}
+
+func TestSyncNode_CleansUpLaunchedPodAfterRelease(t *testing.T) {
+	n := launcherNode()
+	done := newInitPod(testTemplate(), n, StartupTaint, 1)
+	done.Status.Phase = corev1.PodSucceeded
+	other := podWith("ds-pod", "n1", labeledStartup(), nil, nil, nil)
+	other.Namespace = "kube-system"
+	c, client := newControllerWith(n, testTemplate(), done, other)
+	WithInitPodTemplate(testTemplateRef)(c)
+
+	if err := c.syncNode(n); err != nil {
+		t.Fatalf("sync: %v", err)
+	}
+	got, _ := client.CoreV1().Nodes().Get(ctx(), "n1", metav1.GetOptions{})
+	if HasStartupTaint(got) {
+		t.Fatalf("expected node released")
+	}
+	pods := listInitPods(t, client)
+	if len(pods) != 1 || pods[0].Name != "ds-pod" {
+		t.Fatalf("expected only the foreign init pod to remain, got %v", pods)
+	}
+}
+
+func TestInitBackoff(t *testing.T) {
+	for failures, want := range map[int]time.Duration{0: 0, 1: 10 * time.Second, 2: 20 * time.Second, 3: 40 * time.Second, 10: 5 * time.Minute} {
+		if got := initBackoff(failures); got != want {
+			t.Fatalf("initBackoff(%d)=%v want %v", failures, got, want)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package startup
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/util/retry"
+)
+
+// releaseNode drops the startup taint and records completion; false when the taint was absent.
+func releaseNode(n *corev1.Node) bool {
+	newTaints := n.Spec.Taints[:0]
+	changed := false
+	for _, t := range n.Spec.Taints {
+		if isStartupTaint(t) {
+			changed = true
+			continue
+		}
+		newTaints = append(newTaints, t)
+	}
+	if !changed {
+		return false
+	}
+	n.Spec.Taints = newTaints
+	if n.Annotations == nil {
+		n.Annotations = map[string]string{}
+	}
+	now := time.Now()
+	n.Annotations[NodeStartupCompletedAnnotation] = now.UTC().Format(time.RFC3339)
+	n.Annotations[NodeStartupCompletedUIDAnnotation] = string(n.UID)
+	if !n.CreationTimestamp.IsZero() {
+		n.Annotations[NodeStartupDurationAnnotation] = now.Sub(n.CreationTimestamp.Time).Round(time.Second).String()
+	}
+	if at, err := time.Parse(time.RFC3339, n.Annotations[NodeTaintedAtAnnotation]); err == nil {
+		n.Annotations[NodeGatingDurationAnnotation] = now.Sub(at).Round(time.Second).String()
+	}
+	setStateLabel(n, StateReady)
+	return true
+}
+
+// MarkGating records on a node being tainted when, and under which policy, it
+// was gated, and labels it StateGating.
+func MarkGating(n *corev1.Node, policy string) {
+	if n.Annotations == nil {
+		n.Annotations = map[string]string{}
+	}
+	n.Annotations[NodeTaintedAtAnnotation] = time.Now().UTC().Format(time.RFC3339)
+	n.Annotations[NodePolicyAnnotation] = policy
+	setStateLabel(n, StateGating)
+}
+
+func setStateLabel(n *corev1.Node, state string) {
+	if n.Labels == nil {
+		n.Labels = map[string]string{}
+	}
+	n.Labels[StateLabel] = state
+}
+
+// gateNode re-applies the startup taint and drops state from the previous
+// gating round; false when the taint is already present.
+func gateNode(n *corev1.Node, p Policy) bool {
+	if HasStartupTaint(n) {
+		return false
+	}
+	n.Spec.Taints = append(n.Spec.Taints, p.Taint())
+	MarkGating(n, p.Name)
+	delete(n.Annotations, NodeStartupCompletedAnnotation)
+	delete(n.Annotations, NodeStartupCompletedUIDAnnotation)
+	delete(n.Annotations, NodeInitFailuresAnnotation)
+	delete(n.Annotations, NodeReleasedByAnnotation)
+	delete(n.Annotations, NodeGatingDurationAnnotation)
+	delete(n.Annotations, NodeStartupDurationAnnotation)
+	delete(n.Annotations, NodeSatisfiedByAnnotation)
+	// A release is one-shot; re-gating starts a fresh round.
+	delete(n.Annotations, ReleaseAnnotation)
+	for k := range n.Annotations {
+		if strings.HasPrefix(k, ComponentReadyAnnotationPrefix) {
+			delete(n.Annotations, k)
+		}
+	}
+	return true
+}
+
+// Release removes the startup taint by hand, recording completion (so the
+// controller does not re-gate) and who released it. False when the node was not gated.
+func Release(ctx context.Context, client kubernetes.Interface, nodeName, by string) (bool, error) {
+	return updateNode(ctx, client, nodeName, func(n *corev1.Node) bool {
+		if !releaseNode(n) {
+			return false
+		}
+		n.Annotations[NodeReleasedByAnnotation] = by
+		return true
+	})
+}
+
+// Regate re-applies the node's startup taint; the controller then waits for the
+// node's readiness policy again. False when the node was already gated.
+func (c *Controller) Regate(ctx context.Context, nodeName string) (bool, error) {
+	return updateNode(ctx, c.client, nodeName, func(n *corev1.Node) bool {
+		return gateNode(n, c.PolicyFor(n))
+	})
+}
+
+func updateNode(ctx context.Context, client kubernetes.Interface, nodeName string, mutate func(*corev1.Node) bool) (bool, error) {
+	changed := false
+	err := retry.RetryOnConflict(retry.DefaultBackoff, func() error {
+		n, err := client.CoreV1().Nodes().Get(ctx, nodeName, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		if changed = mutate(n); !changed {
+			return nil
+		}
+		_, err = client.CoreV1().Nodes().Update(ctx, n, metav1.UpdateOptions{})
+		return err
+	})
+	return changed, err
+}
@@ -0,0 +1,72 @@
+package startup
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestRelease_RecordsCompletionAndOperator(t *testing.T) {
+	n := makeNode("n1", StartupTaint)
+	n.UID = "uid-1"
+	c, client := newControllerWith(n)
+	changed, err := Release(ctx(), client, "n1", "alice")
+	if err != nil || !changed {
+		t.Fatalf("release changed=%v err=%v", changed, err)
+	}
+	got, _ := client.CoreV1().Nodes().Get(ctx(), "n1", metav1.GetOptions{})
+	if HasStartupTaint(got) || got.Annotations[NodeReleasedByAnnotation] != "alice" || got.Annotations[NodeStartupCompletedUIDAnnotation] != "uid-1" {
+		t.Fatalf("unexpected node after release: taints=%v annotations=%v", got.Spec.Taints, got.Annotations)
+	}
+	// The manual removal must not look like a dropped taint to the controller.
+	c.handleNodeUpdate(n, got)
+	if c.takeDropped(got) {
+		t.Fatalf("manual release treated as dropped taint")
+	}
+	if changed, _ := Release(ctx(), client, "n1", "alice"); changed {
+		t.Fatalf("second release should be a no-op")
+	}
+}
+
+func TestRegate_ClearsPreviousRound(t *testing.T) {
+	n := makeNode("n1")
+	n.Annotations = map[string]string{
+		NodeStartupCompletedAnnotation: "1",
+		NodeReleasedByAnnotation:       "alice",
+		"unrelated":                    "kept",
+	}
+	c, client := newControllerWith(n)
+	changed, err := c.Regate(ctx(), "n1")
+	if err != nil || !changed {
+		t.Fatalf("regate changed=%v err=%v", changed, err)
+	}
+	got, _ := client.CoreV1().Nodes().Get(ctx(), "n1", metav1.GetOptions{})
+	// Only the new round's audit annotations remain besides unrelated ones.
+	if !HasStartupTaint(got) || len(got.Annotations) != 3 || got.Annotations["unrelated"] != "kept" ||
+		got.Annotations[NodePolicyAnnotation] != DefaultPolicyName || got.Annotations[NodeTaintedAtAnnotation] == "" ||
+		got.Labels[StateLabel] != StateGating {
+		t.Fatalf("unexpected node after regate: taints=%v annotations=%v", got.Spec.Taints, got.Annotations)
+	}
+	if changed, _ := c.Regate(ctx(), "n1"); changed {
+		t.Fatalf("second regate should be a no-op")
+	}
+}
+
+func TestBackfill_DryRunDoesNotWrite(t *testing.T) {
+	idle := makeNode("idle")
+	busy := makeNode("busy")
+	work := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default"}, Spec: corev1.PodSpec{NodeName: "busy"}}
+	c, client := newControllerWith(idle, busy, work)
+	nodes, err := c.Backfill(ctx(), true)
+	if err != nil {
+		t.Fatalf("backfill: %v", err)
+	}
+	if len(nodes) != 1 || nodes[0] != "idle" {
+		t.Fatalf("unexpected candidates %v", nodes)
+	}
+	got, _ := client.CoreV1().Nodes().Get(ctx(), "idle", metav1.GetOptions{})
+	if HasStartupTaint(got) {
+		t.Fatalf("dry run must not taint")
+	}
+}
@@ -0,0 +1,71 @@
+package startup
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// Release paths, the "by" label of nodetaintshandler_releases_total.
+const (
+	releasedReady    = "ready"
+	releasedOverride = "override"
+	releasedBreaker  = "breaker"
+)
+
+// metrics are the controller's Prometheus series. Counters are updated as
+// things happen; gauges are read from the caches on every scrape.
+type metrics struct {
+	releases     *prometheus.CounterVec
+	breakerTrips *prometheus.CounterVec
+	gated        *prometheus.Desc
+	queueDepth   *prometheus.Desc
+}
+
+func newMetrics() *metrics {
+	return &metrics{
+		releases: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "nodetaintshandler_releases_total",
+			Help: "Startup taints removed by this replica, by release path (ready, override, breaker).",
+		}, []string{"by"}),
+		breakerTrips: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "nodetaintshandler_breaker_trips_total",
+			Help: "Times the startup breaker tripped, by pool.",
+		}, []string{"pool"}),
+		gated: prometheus.NewDesc("nodetaintshandler_gated_nodes",
+			"Nodes currently carrying the startup taint.", nil, nil),
+		queueDepth: prometheus.NewDesc("nodetaintshandler_release_queue_depth",
+			"Ready nodes waiting for a release slot (--release-rate).", nil, nil),
+	}
+}
+
+// Describe implements prometheus.Collector.
+func (c *Controller) Describe(ch chan<- *prometheus.Desc) {
+	c.metrics.releases.Describe(ch)
+	c.metrics.breakerTrips.Describe(ch)
+	ch <- c.metrics.gated
+	ch <- c.metrics.queueDepth
+}
+
+// Collect implements prometheus.Collector. The gauges are reported once the
+// informer caches have synced.
+func (c *Controller) Collect(ch chan<- prometheus.Metric) {
+	c.metrics.releases.Collect(ch)
+	c.metrics.breakerTrips.Collect(ch)
+	if !c.HasSynced() {
+		return
+	}
+	if nodes, err := c.nodeLister.List(labels.Everything()); err == nil {
+		gated := 0
+		for _, n := range nodes {
+			if HasStartupTaint(n) {
+				gated++
+			}
+		}
+		ch <- prometheus.MustNewConstMetric(c.metrics.gated, prometheus.GaugeValue, float64(gated))
+	}
+	depth := 0
+	if c.releases != nil {
+		depth = c.releases.depth()
+	}
+	ch <- prometheus.MustNewConstMetric(c.metrics.queueDepth, prometheus.GaugeValue, float64(depth))
+}
@@ -0,0 +1,63 @@
+package startup
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	corev1 "k8s.io/api/core/v1"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+func TestMetrics(t *testing.T) {
+	ready := makeNode("n1", StartupTaint)
+	gated := makeNode("n2", StartupTaint)
+	c, _ := newControllerWith(ready, gated, makeNode("n3"),
+		podWith("init-n1", "n1", labeledStartup(), map[string]string{StartPodReadyAnnotation: "true"}, nil, nil))
+	WithReleaseLimit(1, 1)(c)
+
+	if err := c.syncNode(ready); err != nil {
+		t.Fatalf("sync: %v", err)
+	}
+	c.releases.waiting["n4"] = time.Now()
+
+	// Gauges come from the node cache, which holds n1 as it was before the release.
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	for _, n := range []*corev1.Node{gated, makeNode("n3")} {
+		indexer.Add(n)
+	}
+	c.nodeLister = corelisters.NewNodeLister(indexer)
+	c.synced.Store(true)
+
+	want := `
+# HELP nodetaintshandler_gated_nodes Nodes currently carrying the startup taint.
+# TYPE nodetaintshandler_gated_nodes gauge
+nodetaintshandler_gated_nodes 1
+# HELP nodetaintshandler_release_queue_depth Ready nodes waiting for a release slot (--release-rate).
+# TYPE nodetaintshandler_release_queue_depth gauge
+nodetaintshandler_release_queue_depth 1
+# HELP nodetaintshandler_releases_total Startup taints removed by this replica, by release path (ready, override, breaker).
+# TYPE nodetaintshandler_releases_total counter
+nodetaintshandler_releases_total{by="ready"} 1
+`
+	if err := testutil.CollectAndCompare(c, strings.NewReader(want)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMetrics_BreakerTripsAndReleases(t *testing.T) {
+	n1 := poolNode("n1", "a", time.Hour, true)
+	n2 := poolNode("n2", "a", time.Hour, true)
+	c, _ := newControllerWith(n1, n2)
+	WithBreaker(BreakerConfig{MaxGatedNodes: 1, GatedFor: time.Minute, PoolLabel: "pool", ReleaseOnTrip: true})(c)
+
+	c.evaluateBreaker(ctx(), []*corev1.Node{n1, n2}, time.Now())
+	if got := testutil.ToFloat64(c.metrics.breakerTrips.WithLabelValues("a")); got != 1 {
+		t.Fatalf("breaker trips = %v", got)
+	}
+	if got := testutil.ToFloat64(c.metrics.releases.WithLabelValues(releasedBreaker)); got != 2 {
+		t.Fatalf("breaker releases = %v", got)
+	}
+}
@@ -0,0 +1,37 @@
+package startup
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ReadinessOptions turns the readiness flags shared by the controller and the
+// kubectl plugin into Options, so both evaluate nodes the same way.
+func ReadinessOptions(configPath, requiredConditions string, initPodCheck bool) ([]Option, []Policy, error) {
+	var opts []Option
+	var policies []Policy
+	if configPath != "" {
+		cfg, err := LoadConfig(configPath)
+		if err != nil {
+			return nil, nil, err
+		}
+		if policies, err = cfg.BuildPolicies(); err != nil {
+			return nil, nil, err
+		}
+		opts = append(opts, WithPolicies(policies))
+	}
+	reqs, err := ParseConditionRequirements(requiredConditions)
+	if err != nil {
+		return nil, nil, fmt.Errorf("required node conditions: %w", err)
+	}
+	if len(reqs) > 0 {
+		opts = append(opts, WithRequiredNodeConditions(reqs))
+	}
+	if !initPodCheck {
+		if len(reqs) == 0 {
+			return nil, nil, errors.New("disabling the init pod check requires required node conditions")
+		}
+		opts = append(opts, WithDefaultReadiness(nil))
+	}
+	return opts, policies, nil
+}
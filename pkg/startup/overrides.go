@@ -0,0 +1,114 @@
+package startup
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+)
+
+// Operator override annotations (value "true"), honoured by the webhook and the controller.
+const (
+	// SkipAnnotation opts a node out of gating: never tainted, taint removed if present.
+	SkipAnnotation = "startup.k8s.io/skip"
+	// ReleaseAnnotation removes the taint right away regardless of readiness.
+	ReleaseAnnotation = "startup.k8s.io/release"
+	// HoldAnnotation keeps the taint even once the node is ready.
+	HoldAnnotation = "startup.k8s.io/hold"
+)
+
+// Override is the operator override in effect on a node.
+type Override string
+
+const (
+	OverrideNone    Override = ""
+	OverrideSkip    Override = "skip"
+	OverrideRelease Override = "release"
+	OverrideHold    Override = "hold"
+)
+
+// Event reasons for overrides.
+const (
+	EventStartupSkipped  = "StartupSkipped"
+	EventStartupReleased = "StartupReleased"
+	EventStartupHeld     = "StartupHeld"
+)
+
+// NodeOverride returns the override set on the node. Hold wins over skip and
+// release so conflicting annotations keep the node gated.
+func NodeOverride(node *corev1.Node) Override {
+	switch {
+	case node.Annotations[HoldAnnotation] == "true":
+		return OverrideHold
+	case node.Annotations[SkipAnnotation] == "true":
+		return OverrideSkip
+	case node.Annotations[ReleaseAnnotation] == "true":
+		return OverrideRelease
+	}
+	return OverrideNone
+}
+
+// Annotation returns the annotation key that sets the override.
+func (o Override) Annotation() string {
+	switch o {
+	case OverrideSkip:
+		return SkipAnnotation
+	case OverrideRelease:
+		return ReleaseAnnotation
+	case OverrideHold:
+		return HoldAnnotation
+	}
+	return ""
+}
+
+// AKSModeLabel marks AKS system node pools (value "system").
+const AKSModeLabel = "kubernetes.azure.com/mode"
+
+// ExemptSystemNode reports whether node is an AKS system-mode node, which the
+// webhook never gates (it would need kube-system tolerations there) unless held.
+func ExemptSystemNode(node *corev1.Node) bool {
+	return node.Labels[AKSModeLabel] == "system" && NodeOverride(node) != OverrideHold
+}
+
+// Ungated reports whether the override keeps the node free of the startup taint.
+func (o Override) Ungated() bool {
+	return o == OverrideSkip || o == OverrideRelease
+}
+
+// releaseOverride removes the taint of a node opted out via skip/release.
+func (c *Controller) releaseOverride(node *corev1.Node, ov Override) error {
+	changed, err := Release(context.TODO(), c.client, node.Name, "override:"+ov.Annotation())
+	if err != nil || !changed {
+		return err
+	}
+	c.metrics.releases.WithLabelValues(releasedOverride).Inc()
+	reason := EventStartupReleased
+	if ov == OverrideSkip {
+		reason = EventStartupSkipped
+	}
+	klog.Infof("Removed startup taint from node %s: %s=true", node.Name, ov.Annotation())
+	c.eventf(node, corev1.EventTypeNormal, reason, "Startup taint removed by override %s=true", ov.Annotation())
+	c.forgetOverride(node)
+	return nil
+}
+
+// noteOverride emits an event the first time this process sees a node under ov.
+func (c *Controller) noteOverride(node *corev1.Node, ov Override, reason, format string, args ...interface{}) {
+	c.mu.Lock()
+	seen := c.overrides[node.UID] == ov
+	c.overrides[node.UID] = ov
+	c.mu.Unlock()
+	if seen {
+		return
+	}
+	msg := fmt.Sprintf(format, args...)
+	klog.Infof("Node %s: %s", node.Name, msg)
+	c.eventf(node, corev1.EventTypeNormal, reason, "%s", msg)
+}
+
+func (c *Controller) forgetOverride(node *corev1.Node) {
+	c.mu.Lock()
+	delete(c.overrides, node.UID)
+	c.mu.Unlock()
+}
@@ -0,0 +1,109 @@
+package startup
+
+import (
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+func TestNodeOverride_Precedence(t *testing.T) {
+	n := makeNode("n1")
+	if NodeOverride(n) != OverrideNone {
+		t.Fatalf("expected no override")
+	}
+	n.Annotations = map[string]string{ReleaseAnnotation: "true", SkipAnnotation: "true"}
+	if NodeOverride(n) != OverrideSkip {
+		t.Fatalf("skip should win over release")
+	}
+	n.Annotations[HoldAnnotation] = "true"
+	if NodeOverride(n) != OverrideHold {
+		t.Fatalf("hold should win")
+	}
+	n.Annotations = map[string]string{HoldAnnotation: "false"}
+	if NodeOverride(n) != OverrideNone {
+		t.Fatalf("only \"true\" counts")
+	}
+}
+
+func TestSyncNode_ReleaseOverrideRemovesTaintWithoutReadiness(t *testing.T) {
+	n := makeNode("n1", StartupTaint)
+	n.UID = "uid-1"
+	n.Annotations = map[string]string{ReleaseAnnotation: "true"}
+	c, client := newControllerWith(n)
+	rec := record.NewFakeRecorder(5)
+	WithRecorder(rec)(c)
+	if err := c.syncNode(n); err != nil {
+		t.Fatalf("sync: %v", err)
+	}
+	got, _ := client.CoreV1().Nodes().Get(ctx(), "n1", metav1.GetOptions{})
+	if HasStartupTaint(got) || got.Annotations[NodeReleasedByAnnotation] != "override:"+ReleaseAnnotation || !completionRecorded(got) {
+		t.Fatalf("unexpected node: taints=%v annotations=%v", got.Spec.Taints, got.Annotations)
+	}
+	if e := <-rec.Events; !strings.Contains(e, EventStartupReleased) {
+		t.Fatalf("unexpected event %q", e)
+	}
+	// Re-gating clears the one-shot release.
+	if _, err := c.Regate(ctx(), "n1"); err != nil {
+		t.Fatal(err)
+	}
+	got, _ = client.CoreV1().Nodes().Get(ctx(), "n1", metav1.GetOptions{})
+	if _, ok := got.Annotations[ReleaseAnnotation]; ok {
+		t.Fatalf("release annotation should be cleared by regate")
+	}
+}
+
+func TestSyncNode_HoldKeepsTaintWhenReady(t *testing.T) {
+	n := makeNode("n1", StartupTaint)
+	n.Annotations = map[string]string{HoldAnnotation: "true"}
+	ready := podWith("init", "n1", labeledStartup(), map[string]string{StartPodReadyAnnotation: "true"}, nil, nil)
+	c, client := newControllerWith(n, ready)
+	rec := record.NewFakeRecorder(5)
+	WithRecorder(rec)(c)
+	for i := 0; i < 2; i++ {
+		if err := c.syncNode(n); err != nil {
+			t.Fatalf("sync: %v", err)
+		}
+	}
+	got, _ := client.CoreV1().Nodes().Get(ctx(), "n1", metav1.GetOptions{})
+	if !HasStartupTaint(got) {
+		t.Fatalf("held node released")
+	}
+	if len(rec.Events) != 1 || !strings.Contains(<-rec.Events, EventStartupHeld) {
+		t.Fatalf("expected exactly one %s event", EventStartupHeld)
+	}
+
+	delete(got.Annotations, HoldAnnotation)
+	if err := c.syncNode(got); err != nil {
+		t.Fatalf("sync: %v", err)
+	}
+	got, _ = client.CoreV1().Nodes().Get(ctx(), "n1", metav1.GetOptions{})
+	if HasStartupTaint(got) {
+		t.Fatalf("node should be released once hold is lifted")
+	}
+}
+
+func TestSkip_NeverRegatedOrBackfilled(t *testing.T) {
+	n := makeNode("n1")
+	n.Annotations = map[string]string{SkipAnnotation: "true"}
+	c, client := newControllerWith(n)
+	rec := record.NewFakeRecorder(5)
+	WithRecorder(rec)(c)
+
+	// Taint dropped without completion would normally be re-gated.
+	tainted := n.DeepCopy()
+	tainted.Spec.Taints = []corev1.Taint{StartupTaint}
+	c.handleNodeUpdate(tainted, n)
+	if nodes, _ := c.Backfill(ctx(), true); len(nodes) != 0 {
+		t.Fatalf("skipped node would be backfilled: %v", nodes)
+	}
+	got, _ := client.CoreV1().Nodes().Get(ctx(), "n1", metav1.GetOptions{})
+	if HasStartupTaint(got) {
+		t.Fatalf("skipped node re-gated")
+	}
+	if len(rec.Events) != 1 || !strings.Contains(<-rec.Events, EventStartupSkipped) {
+		t.Fatalf("expected one %s event", EventStartupSkipped)
+	}
+}
@@ -0,0 +1,273 @@
+package startup
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/yaml"
+)
+
+// DefaultPolicyName names the policy applied to nodes no configured policy selects.
+const DefaultPolicyName = "default"
+
+// Policy selects nodes and decides when their startup gating completes.
+type Policy struct {
+	Name      string
+	Selector  labels.Selector
+	Readiness ReadinessChecker
+	// InitPodTemplate, when set, overrides the controller-wide init pod template.
+	InitPodTemplate *types.NamespacedName
+	// TaintEffect of the startup taint for matching nodes (default StartupTaint.Effect).
+	TaintEffect corev1.TaintEffect
+}
+
+// Taint is the startup taint applied to nodes of this policy.
+func (p Policy) Taint() corev1.Taint {
+	t := StartupTaint
+	if p.TaintEffect != "" {
+		t.Effect = p.TaintEffect
+	}
+	return t
+}
+
+// DefaultPolicy matches every node and uses DefaultReadiness.
+func DefaultPolicy() Policy {
+	return Policy{Name: DefaultPolicyName, Selector: labels.Everything(), Readiness: DefaultReadiness}
+}
+
+// Config is the on-disk controller configuration (YAML or JSON, see --config).
+type Config struct {
+	// Policies are evaluated in order; the first whose nodeSelector matches wins.
+	Policies []PolicySpec `json:"policies,omitempty"`
+}
+
+type PolicySpec struct {
+	Name string `json:"name"`
+	// NodeSelector limits the policy to matching nodes; empty selects all.
+	NodeSelector *metav1.LabelSelector `json:"nodeSelector,omitempty"`
+	// Readiness may be omitted when RequiredNodeConditions alone gate the node.
+	Readiness ReadinessSpec `json:"readiness,omitempty"`
+	// RequiredNodeConditions must all hold in addition to Readiness.
+	RequiredNodeConditions []ConditionSpec `json:"requiredNodeConditions,omitempty"`
+	// InitPodTemplate names a PodTemplate the controller launches on each gated node.
+	InitPodTemplate *ObjectRef `json:"initPodTemplate,omitempty"`
+	// TaintEffect is NoSchedule (default), PreferNoSchedule or NoExecute.
+	TaintEffect corev1.TaintEffect `json:"taintEffect,omitempty"`
+}
+
+type ObjectRef struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+}
+
+// ReadinessSpec describes one checker; exactly one field must be set.
+type ReadinessSpec struct {
+	AllOf []ReadinessSpec `json:"allOf,omitempty"`
+	AnyOf []ReadinessSpec `json:"anyOf,omitempty"`
+
+	PodReady          *struct{}      `json:"podReady,omitempty"`
+	PodAnnotation     *KeyValue      `json:"podAnnotation,omitempty"`
+	PodSucceeded      *struct{}      `json:"podSucceeded,omitempty"`
+	ContainerExitZero *ContainerRef  `json:"containerExitZero,omitempty"`
+	PodCondition      *ConditionSpec `json:"podCondition,omitempty"`
+	NodeLabel         *KeyValue      `json:"nodeLabel,omitempty"`
+	NodeCondition     *ConditionSpec `json:"nodeCondition,omitempty"`
+	ComponentReady    *ContainerRef  `json:"componentReady,omitempty"`
+}
+
+type KeyValue struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+// ContainerRef names a container (containerExitZero) or reported component (componentReady).
+type ContainerRef struct {
+	Name string `json:"name"`
+}
+
+type ConditionSpec struct {
+	Type string `json:"type"`
+	// Status defaults to "True".
+	Status corev1.ConditionStatus `json:"status,omitempty"`
+}
+
+// LoadConfig reads a YAML/JSON config file.
+func LoadConfig(path string) (*Config, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	cfg := &Config{}
+	if err := yaml.UnmarshalStrict(raw, cfg); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// BuildPolicies validates the config and returns policies in evaluation order.
+func (c *Config) BuildPolicies() ([]Policy, error) {
+	out := make([]Policy, 0, len(c.Policies))
+	seen := map[string]bool{}
+	for i, ps := range c.Policies {
+		if ps.Name == "" {
+			return nil, fmt.Errorf("policies[%d]: name required", i)
+		}
+		if seen[ps.Name] {
+			return nil, fmt.Errorf("policies[%d]: duplicate name %q", i, ps.Name)
+		}
+		seen[ps.Name] = true
+		sel := labels.Everything()
+		if ps.NodeSelector != nil {
+			var err error
+			if sel, err = metav1.LabelSelectorAsSelector(ps.NodeSelector); err != nil {
+				return nil, fmt.Errorf("policy %s: nodeSelector: %w", ps.Name, err)
+			}
+		}
+		checker, err := ps.readiness()
+		if err != nil {
+			return nil, fmt.Errorf("policy %s: %w", ps.Name, err)
+		}
+		policy := Policy{Name: ps.Name, Selector: sel, Readiness: checker}
+		switch ps.TaintEffect {
+		case "", corev1.TaintEffectNoSchedule, corev1.TaintEffectPreferNoSchedule, corev1.TaintEffectNoExecute:
+			policy.TaintEffect = ps.TaintEffect
+		default:
+			return nil, fmt.Errorf("policy %s: unsupported taintEffect %q", ps.Name, ps.TaintEffect)
+		}
+		if ref := ps.InitPodTemplate; ref != nil {
+			if ref.Namespace == "" || ref.Name == "" {
+				return nil, fmt.Errorf("policy %s: initPodTemplate needs namespace and name", ps.Name)
+			}
+			policy.InitPodTemplate = &types.NamespacedName{Namespace: ref.Namespace, Name: ref.Name}
+		}
+		out = append(out, policy)
+	}
+	return out, nil
+}
+
+func (ps PolicySpec) readiness() (ReadinessChecker, error) {
+	var reqs []ConditionRequirement
+	for i, cs := range ps.RequiredNodeConditions {
+		if cs.Type == "" {
+			return nil, fmt.Errorf("requiredNodeConditions[%d]: type required", i)
+		}
+		status := cs.Status
+		if status == "" {
+			status = corev1.ConditionTrue
+		}
+		reqs = append(reqs, ConditionRequirement{Type: corev1.NodeConditionType(cs.Type), Status: status})
+	}
+	if len(reqs) > 0 && reflect.DeepEqual(ps.Readiness, ReadinessSpec{}) {
+		return RequireNodeConditions(reqs...), nil
+	}
+	checker, err := ps.Readiness.Build()
+	if err != nil {
+		return nil, fmt.Errorf("readiness: %w", err)
+	}
+	if len(reqs) > 0 {
+		checker = AllOf(checker, RequireNodeConditions(reqs...))
+	}
+	return checker, nil
+}
+
+// Build turns the spec into a ReadinessChecker.
+func (s ReadinessSpec) Build() (ReadinessChecker, error) {
+	var built []ReadinessChecker
+	set := 0
+	if len(s.AllOf) > 0 || len(s.AnyOf) > 0 {
+		children := s.AllOf
+		if len(s.AnyOf) > 0 {
+			children = s.AnyOf
+		}
+		for i, child := range children {
+			c, err := child.Build()
+			if err != nil {
+				return nil, fmt.Errorf("[%d]: %w", i, err)
+			}
+			built = append(built, c)
+		}
+	}
+	var checker ReadinessChecker
+	pick := func(c ReadinessChecker) {
+		set++
+		checker = c
+	}
+	if len(s.AllOf) > 0 {
+		pick(AllOf(built...))
+	}
+	if len(s.AnyOf) > 0 {
+		pick(AnyOf(built...))
+	}
+	if s.PodReady != nil {
+		pick(PodReady())
+	}
+	if s.PodAnnotation != nil {
+		if s.PodAnnotation.Key == "" {
+			return nil, errors.New("podAnnotation.key required")
+		}
+		pick(PodAnnotation(s.PodAnnotation.Key, s.PodAnnotation.Value))
+	}
+	if s.PodSucceeded != nil {
+		pick(PodSucceeded())
+	}
+	if s.ContainerExitZero != nil {
+		if s.ContainerExitZero.Name == "" {
+			return nil, errors.New("containerExitZero.name required")
+		}
+		pick(ContainerExitedZero(s.ContainerExitZero.Name))
+	}
+	if s.PodCondition != nil {
+		if s.PodCondition.Type == "" {
+			return nil, errors.New("podCondition.type required")
+		}
+		pick(PodConditionTrue(corev1.PodConditionType(s.PodCondition.Type)))
+	}
+	if s.NodeLabel != nil {
+		if s.NodeLabel.Key == "" {
+			return nil, errors.New("nodeLabel.key required")
+		}
+		pick(NodeLabel(s.NodeLabel.Key, s.NodeLabel.Value))
+	}
+	if s.NodeCondition != nil {
+		if s.NodeCondition.Type == "" {
+			return nil, errors.New("nodeCondition.type required")
+		}
+		status := s.NodeCondition.Status
+		if status == "" {
+			status = corev1.ConditionTrue
+		}
+		pick(NodeCondition(corev1.NodeConditionType(s.NodeCondition.Type), status))
+	}
+	if s.ComponentReady != nil {
+		if errs := validation.IsDNS1123Label(s.ComponentReady.Name); len(errs) > 0 {
+			return nil, fmt.Errorf("componentReady.name: %s", strings.Join(errs, "; "))
+		}
+		pick(ComponentReady(s.ComponentReady.Name))
+	}
+	switch set {
+	case 0:
+		return nil, errors.New("empty readiness spec")
+	case 1:
+		return checker, nil
+	default:
+		return nil, errors.New("readiness spec must set exactly one field (wrap several in allOf/anyOf)")
+	}
+}
+
+// PolicyFor returns the first configured policy selecting the node, else the default.
+func (c *Controller) PolicyFor(node *corev1.Node) Policy {
+	for _, p := range c.policies {
+		if p.Selector.Matches(labels.Set(node.Labels)) {
+			return p
+		}
+	}
+	return c.defaultPolicy
+}
@@ -0,0 +1,161 @@
+package startup
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const testConfig = `
+policies:
+- name: gpu
+  nodeSelector:
+    matchLabels:
+      accelerator: nvidia
+  readiness:
+    allOf:
+    - containerExitZero: {name: install-driver}
+    - nodeLabel: {key: nvidia.com/gpu.present, value: "true"}
+- name: general
+  readiness:
+    anyOf:
+    - podReady: {}
+    - podSucceeded: {}
+`
+
+func writeConfig(t *testing.T, body string) string {
+	t.Helper()
+	p := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(p, []byte(body), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestLoadConfig_BuildPolicies(t *testing.T) {
+	cfg, err := LoadConfig(writeConfig(t, testConfig))
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	policies, err := cfg.BuildPolicies()
+	if err != nil {
+		t.Fatalf("build: %v", err)
+	}
+	c, _ := newControllerWith()
+	WithPolicies(policies)(c)
+
+	gpu := makeNode("gpu-1", StartupTaint)
+	gpu.Labels = map[string]string{"accelerator": "nvidia"}
+	if got := c.PolicyFor(gpu).Name; got != "gpu" {
+		t.Fatalf("gpu node policy=%s", got)
+	}
+	if got := c.PolicyFor(makeNode("cpu-1")).Name; got != "general" {
+		t.Fatalf("cpu node policy=%s", got)
+	}
+
+	// Without a catch-all policy, unmatched nodes fall back to the default.
+	c.policies = policies[:1]
+	if got := c.PolicyFor(makeNode("cpu-1")).Name; got != DefaultPolicyName {
+		t.Fatalf("fallback policy=%s", got)
+	}
+}
+
+func TestBuildPolicies_Invalid(t *testing.T) {
+	cases := map[string]string{
+		"unknown field":  "policies:\n- name: a\n  readiness: {podReadyy: {}}\n",
+		"empty spec":     "policies:\n- name: a\n  readiness: {}\n",
+		"two fields":     "policies:\n- name: a\n  readiness: {podReady: {}, podSucceeded: {}}\n",
+		"missing name":   "policies:\n- readiness: {podReady: {}}\n",
+		"duplicate name": "policies:\n- name: a\n  readiness: {podReady: {}}\n- name: a\n  readiness: {podReady: {}}\n",
+		"nested invalid": "policies:\n- name: a\n  readiness: {allOf: [{nodeLabel: {value: x}}]}\n",
+		"bad component":  "policies:\n- name: a\n  readiness: {componentReady: {name: Bad_Name}}\n",
+		"bad effect":     "policies:\n- name: a\n  taintEffect: NoRun\n  readiness: {podReady: {}}\n",
+		"bad selector":   "policies:\n- name: a\n  nodeSelector: {matchExpressions: [{key: k, operator: Bogus}]}\n  readiness: {podReady: {}}\n",
+	}
+	for name, body := range cases {
+		t.Run(name, func(t *testing.T) {
+			cfg, err := LoadConfig(writeConfig(t, body))
+			if err == nil {
+				_, err = cfg.BuildPolicies()
+			}
+			if err == nil {
+				t.Fatalf("expected error")
+			}
+		})
+	}
+}
+
+func TestSyncNode_UsesPolicyReadiness(t *testing.T) {
+	n := makeNode("n1", StartupTaint)
+	n.Labels = map[string]string{"pool": "batch"}
+	n.Labels["example.com/prepared"] = "true"
+	agent := podWith("agent", "n1", labeledStartup(), nil, []corev1.ContainerStatus{{Name: "agent", Ready: false}}, nil)
+	agent.Status.Phase = corev1.PodRunning
+	c, client := newControllerWith(n, agent)
+
+	// Default policy wants a Ready init pod.
+	if err := c.syncNode(n); err != nil {
+		t.Fatalf("sync: %v", err)
+	}
+	if got, _ := client.CoreV1().Nodes().Get(ctx(), "n1", metav1.GetOptions{}); !HasStartupTaint(got) {
+		t.Fatalf("default policy should keep taint")
+	}
+
+	cfg, err := LoadConfig(writeConfig(t, "policies:\n- name: batch\n  nodeSelector: {matchLabels: {pool: batch}}\n  readiness: {nodeLabel: {key: example.com/prepared, value: \"true\"}}\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	policies, err := cfg.BuildPolicies()
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.policies = policies
+	_, pending, _ := c.evaluate(n)
+	if len(pending) != 0 {
+		t.Fatalf("unexpected pending: %v", pending)
+	}
+	if err := c.syncNode(n); err != nil {
+		t.Fatalf("sync: %v", err)
+	}
+	if got, _ := client.CoreV1().Nodes().Get(ctx(), "n1", metav1.GetOptions{}); HasStartupTaint(got) {
+		t.Fatalf("batch policy should release node")
+	}
+}
+
+func TestPolicy_TaintEffect(t *testing.T) {
+	body := "policies:\n- name: evict\n  nodeSelector: {matchLabels: {pool: batch}}\n  taintEffect: NoExecute\n  readiness: {podReady: {}}\n"
+	cfg, err := LoadConfig(writeConfig(t, body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	policies, err := cfg.BuildPolicies()
+	if err != nil {
+		t.Fatal(err)
+	}
+	batch := makeNode("batch-1")
+	batch.Labels = map[string]string{"pool": "batch"}
+	c, client := newControllerWith(batch, makeNode("other-1"))
+	WithPolicies(policies)(c)
+
+	if got := c.TaintFor(batch); got.Effect != corev1.TaintEffectNoExecute || got.Key != StartupTaint.Key {
+		t.Fatalf("batch taint=%v", got)
+	}
+	if got := c.TaintFor(makeNode("other-1")); got != StartupTaint {
+		t.Fatalf("default taint=%v", got)
+	}
+
+	// Regating applies the policy's effect; any effect counts as the startup taint.
+	if _, err := c.Regate(ctx(), "batch-1"); err != nil {
+		t.Fatal(err)
+	}
+	got, _ := client.CoreV1().Nodes().Get(ctx(), "batch-1", metav1.GetOptions{})
+	if len(got.Spec.Taints) != 1 || got.Spec.Taints[0].Effect != corev1.TaintEffectNoExecute || !HasStartupTaint(got) {
+		t.Fatalf("unexpected taints %v", got.Spec.Taints)
+	}
+	if !releaseNode(got) || len(got.Spec.Taints) != 0 {
+		t.Fatalf("NoExecute startup taint not removed: %v", got.Spec.Taints)
+	}
+}
@@ -0,0 +1,161 @@
+package startup
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+)
+
+// EventWaitingForReleaseSlot is emitted when a ready node queues for the release limiter.
+const EventWaitingForReleaseSlot = "WaitingForReleaseSlot"
+
+// releaseLimiter spaces out taint removals with a token bucket. Ready nodes
+// wait in a FIFO ordered by creation time; only the head may take a token.
+type releaseLimiter struct {
+	mu      sync.Mutex
+	limiter *rate.Limiter
+	waiting map[string]time.Time
+	now     func() time.Time
+}
+
+func newReleaseLimiter(r rate.Limit, burst int) *releaseLimiter {
+	return &releaseLimiter{limiter: rate.NewLimiter(r, burst), waiting: map[string]time.Time{}, now: time.Now}
+}
+
+// WithReleaseLimit caps taint removals to r per second with the given burst
+// (r <= 0 disables the limit).
+func WithReleaseLimit(r float64, burst int) Option {
+	return func(c *Controller) {
+		if r <= 0 {
+			c.releases = nil
+			return
+		}
+		if burst < 1 {
+			burst = 1
+		}
+		c.releases = newReleaseLimiter(rate.Limit(r), burst)
+	}
+}
+
+// admit reports whether the node may be released now. Otherwise it returns how
+// long to wait (0: not at the head, it is requeued when it gets there) and
+// whether the node just joined the queue.
+func (l *releaseLimiter) admit(node *corev1.Node) (ok bool, wait time.Duration, joined bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, in := l.waiting[node.Name]; !in {
+		l.waiting[node.Name] = node.CreationTimestamp.Time
+		joined = true
+	}
+	if l.headLocked() != node.Name {
+		return false, 0, joined
+	}
+	now := l.now()
+	r := l.limiter.ReserveN(now, 1)
+	if d := r.DelayFrom(now); d > 0 {
+		r.CancelAt(now)
+		return false, d, joined
+	}
+	delete(l.waiting, node.Name)
+	return true, 0, joined
+}
+
+// forget drops a node that left the queue without a release and returns the
+// node now at the head, if any.
+func (l *releaseLimiter) forget(name string) string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.waiting, name)
+	return l.headLocked()
+}
+
+// position is the node's 1-based place in the queue (0 when not queued) and the queue length.
+func (l *releaseLimiter) position(name string) (int, int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, in := l.waiting[name]; !in {
+		return 0, len(l.waiting)
+	}
+	order := l.orderLocked()
+	return indexOf(order, name) + 1, len(order)
+}
+
+// depth is the number of nodes waiting for a slot.
+func (l *releaseLimiter) depth() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.waiting)
+}
+
+func (l *releaseLimiter) headLocked() string {
+	var head string
+	var headAt time.Time
+	for name, at := range l.waiting {
+		if head == "" || at.Before(headAt) || (at.Equal(headAt) && name < head) {
+			head, headAt = name, at
+		}
+	}
+	return head
+}
+
+func (l *releaseLimiter) orderLocked() []string {
+	order := make([]string, 0, len(l.waiting))
+	for name := range l.waiting {
+		order = append(order, name)
+	}
+	sort.Slice(order, func(i, j int) bool {
+		a, b := l.waiting[order[i]], l.waiting[order[j]]
+		if !a.Equal(b) {
+			return a.Before(b)
+		}
+		return order[i] < order[j]
+	})
+	return order
+}
+
+func indexOf(s []string, v string) int {
+	for i := range s {
+		if s[i] == v {
+			return i
+		}
+	}
+	return -1
+}
+
+// forgetRelease removes the node from the release queue and wakes the next head.
+func (c *Controller) forgetRelease(name string) {
+	if c.releases == nil {
+		return
+	}
+	if next := c.releases.forget(name); next != "" && next != name && c.queue != nil {
+		c.queue.Add(next)
+	}
+}
+
+// admitRelease asks the limiter for a release slot. A node that has to wait is
+// requeued for when its token is due (at the head) or when it reaches the head.
+func (c *Controller) admitRelease(node *corev1.Node) bool {
+	if c.releases == nil {
+		return true
+	}
+	ok, wait, joined := c.releases.admit(node)
+	if ok {
+		if next := c.releases.forget(""); next != "" && c.queue != nil {
+			c.queue.Add(next)
+		}
+		return true
+	}
+	if joined {
+		pos, total := c.releases.position(node.Name)
+		klog.Infof("Node %s ready; waiting for release slot (position %d of %d)", node.Name, pos, total)
+		c.eventf(node, corev1.EventTypeNormal, EventWaitingForReleaseSlot, "Ready; waiting for a release slot (position %d of %d)", pos, total)
+	}
+	if wait > 0 && c.queue != nil {
+		c.queue.AddAfter(node.Name, wait)
+	}
+	return false
+}
@@ -0,0 +1,102 @@
+package startup
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+)
+
+func TestReleaseLimit_FIFOByCreationAndTokenBucket(t *testing.T) {
+	base := time.Now().Add(-time.Hour)
+	var objs []runtime.Object
+	nodes := map[string]*corev1.Node{}
+	for i, name := range []string{"n-old", "n-mid", "n-new"} {
+		n := makeNode(name, StartupTaint)
+		n.CreationTimestamp = metav1.NewTime(base.Add(time.Duration(i) * time.Minute))
+		nodes[name] = n
+		objs = append(objs, n, podWith("init-"+name, name, labeledStartup(), map[string]string{StartPodReadyAnnotation: "true"}, nil, nil))
+	}
+	c, client := newControllerWith(objs...)
+	rec := record.NewFakeRecorder(10)
+	WithRecorder(rec)(c)
+	WithReleaseLimit(1.0/60, 1)(c)
+	clock := time.Now()
+	c.releases.now = func() time.Time { return clock }
+	c.synced.Store(true)
+
+	tainted := func(name string) bool {
+		got, _ := client.CoreV1().Nodes().Get(ctx(), name, metav1.GetOptions{})
+		return HasStartupTaint(got)
+	}
+	sync := func(name string) {
+		t.Helper()
+		if err := c.syncNode(nodes[name]); err != nil {
+			t.Fatalf("sync %s: %v", name, err)
+		}
+	}
+
+	// Bucket empty: nodes becoming ready queue up, oldest first regardless of readiness order.
+	c.releases.limiter.ReserveN(clock, 1)
+	for _, name := range []string{"n-new", "n-mid", "n-old"} {
+		sync(name)
+		if !tainted(name) {
+			t.Fatalf("%s released without a token", name)
+		}
+	}
+	if pending, _ := c.PendingComponents(nodes["n-new"]); len(pending) != 1 || !strings.Contains(pending[0], "position 3 of 3") {
+		t.Fatalf("unexpected pending for n-new: %v", pending)
+	}
+
+	for i, want := range []string{"n-old", "n-mid", "n-new"} {
+		clock = clock.Add(time.Minute)
+		for _, name := range []string{"n-new", "n-mid", "n-old"} {
+			if tainted(name) {
+				sync(name)
+			}
+		}
+		if tainted(want) {
+			t.Fatalf("%s should be released next", want)
+		}
+		remaining := 0
+		for _, name := range []string{"n-new", "n-mid", "n-old"} {
+			if tainted(name) {
+				remaining++
+			}
+		}
+		if remaining != 2-i {
+			t.Fatalf("after tick %d: %d nodes still tainted, want %d", i+1, remaining, 2-i)
+		}
+	}
+
+	var waiting int
+	for len(rec.Events) > 0 {
+		if strings.Contains(<-rec.Events, EventWaitingForReleaseSlot) {
+			waiting++
+		}
+	}
+	if waiting != 3 {
+		t.Fatalf("expected one %s event per queued node, got %d", EventWaitingForReleaseSlot, waiting)
+	}
+}
+
+func TestReleaseLimit_NotReadyNodeLeavesQueue(t *testing.T) {
+	l := newReleaseLimiter(1, 1)
+	l.limiter.ReserveN(time.Now(), 1) // drain the burst
+	for i := 0; i < 3; i++ {
+		n := makeNode(fmt.Sprintf("n%d", i))
+		n.CreationTimestamp = metav1.NewTime(time.Unix(int64(i), 0))
+		l.admit(n)
+	}
+	if next := l.forget("n0"); next != "n1" {
+		t.Fatalf("head after forget=%q", next)
+	}
+	if pos, total := l.position("n2"); pos != 2 || total != 2 {
+		t.Fatalf("position=%d/%d", pos, total)
+	}
+}
@@ -0,0 +1,189 @@
+package startup
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// ReadinessInput is what a ReadinessChecker sees for one gated node.
+type ReadinessInput struct {
+	Node *corev1.Node
+	// Pods are the init pods (StartPodLabelKey=StartPodLabelValue) bound to the node.
+	Pods []*corev1.Pod
+}
+
+// ReadinessChecker decides whether a node's startup work is complete. When not
+// ready it returns human-readable reasons (what is still pending); when ready it
+// may say what satisfied it (recorded in startup.k8s.io/satisfiedBy).
+type ReadinessChecker interface {
+	Ready(in ReadinessInput) (bool, []string)
+}
+
+// ReadinessFunc adapts a function into a ReadinessChecker.
+type ReadinessFunc func(in ReadinessInput) (bool, []string)
+
+func (f ReadinessFunc) Ready(in ReadinessInput) (bool, []string) { return f(in) }
+
+// DefaultReadiness is the original rule: any init pod with the ready annotation,
+// in phase Succeeded, or with all containers Ready plus the PodReady condition.
+// Failed init pods never satisfy it and count against --init-retries.
+var DefaultReadiness = PodReady()
+
+// podCheck lifts a per-pod predicate to a node check: satisfied when any init pod
+// passes. Reasons name each pod that does not, or the pod that passed.
+func podCheck(pred func(p *corev1.Pod) (bool, string)) ReadinessChecker {
+	return ReadinessFunc(func(in ReadinessInput) (bool, []string) {
+		if len(in.Pods) == 0 {
+			return false, []string{fmt.Sprintf("no init pod (%s=%s) on node yet", StartPodLabelKey, StartPodLabelValue)}
+		}
+		var reasons []string
+		for _, p := range in.Pods {
+			ok, reason := pred(p)
+			if ok {
+				return true, []string{fmt.Sprintf("%s/%s: %s", p.Namespace, p.Name, reason)}
+			}
+			reasons = append(reasons, fmt.Sprintf("%s/%s: %s", p.Namespace, p.Name, reason))
+		}
+		return false, reasons
+	})
+}
+
+// PodReady: annotation shortcut, a Succeeded (run-to-completion) pod, or all
+// containers Ready plus the PodReady condition. A Failed pod is not ready; the
+// controller counts it against the node's init retries.
+func PodReady() ReadinessChecker {
+	return podCheck(podReady)
+}
+
+// PodAnnotation is satisfied when an init pod carries annotation key=value.
+func PodAnnotation(key, value string) ReadinessChecker {
+	return podCheck(func(p *corev1.Pod) (bool, string) {
+		if p.Annotations[key] == value {
+			return true, fmt.Sprintf("annotation %s=%s", key, value)
+		}
+		return false, fmt.Sprintf("annotation %s!=%s", key, value)
+	})
+}
+
+// PodSucceeded is satisfied by a run-to-completion init pod (Job or bare Pod) in phase Succeeded.
+func PodSucceeded() ReadinessChecker {
+	return podCheck(func(p *corev1.Pod) (bool, string) {
+		if p.Status.Phase == corev1.PodSucceeded {
+			return true, "phase Succeeded"
+		}
+		return false, fmt.Sprintf("phase %s", phaseOrPending(p.Status.Phase))
+	})
+}
+
+// ContainerExitedZero is satisfied when the named container of an init pod terminated with exit code 0.
+func ContainerExitedZero(container string) ReadinessChecker {
+	return podCheck(func(p *corev1.Pod) (bool, string) {
+		for _, cs := range append(append([]corev1.ContainerStatus{}, p.Status.InitContainerStatuses...), p.Status.ContainerStatuses...) {
+			if cs.Name != container {
+				continue
+			}
+			if t := cs.State.Terminated; t != nil {
+				if t.ExitCode == 0 {
+					return true, fmt.Sprintf("container %s exited 0", container)
+				}
+				return false, fmt.Sprintf("container %s exited %d", container, t.ExitCode)
+			}
+			return false, fmt.Sprintf("container %s not terminated", container)
+		}
+		return false, fmt.Sprintf("container %s not found", container)
+	})
+}
+
+// PodConditionTrue is satisfied when an init pod reports condition type=True (e.g. a readiness gate).
+func PodConditionTrue(condType corev1.PodConditionType) ReadinessChecker {
+	return podCheck(func(p *corev1.Pod) (bool, string) {
+		for _, c := range p.Status.Conditions {
+			if c.Type == condType && c.Status == corev1.ConditionTrue {
+				return true, fmt.Sprintf("condition %s True", condType)
+			}
+		}
+		return false, fmt.Sprintf("condition %s not True", condType)
+	})
+}
+
+// NodeLabel is satisfied when the node carries label key=value (set by an init agent).
+func NodeLabel(key, value string) ReadinessChecker {
+	return ReadinessFunc(func(in ReadinessInput) (bool, []string) {
+		if v, ok := in.Node.Labels[key]; ok && v == value {
+			return true, []string{fmt.Sprintf("node label %s=%s", key, value)}
+		}
+		return false, []string{fmt.Sprintf("node label %s!=%s", key, value)}
+	})
+}
+
+// NodeCondition is satisfied when the node reports condition type with the given status.
+func NodeCondition(condType corev1.NodeConditionType, status corev1.ConditionStatus) ReadinessChecker {
+	return ReadinessFunc(func(in ReadinessInput) (bool, []string) {
+		for _, c := range in.Node.Status.Conditions {
+			if c.Type == condType {
+				if c.Status == status {
+					return true, []string{fmt.Sprintf("node condition %s=%s", condType, status)}
+				}
+				return false, []string{fmt.Sprintf("node condition %s=%s, want %s", condType, c.Status, status)}
+			}
+		}
+		return false, []string{fmt.Sprintf("node condition %s not reported", condType)}
+	})
+}
+
+// ComponentReady is satisfied once an init agent reported the component ready
+// on the node (see pkg/report).
+func ComponentReady(component string) ReadinessChecker {
+	key := ComponentReadyAnnotation(component)
+	return ReadinessFunc(func(in ReadinessInput) (bool, []string) {
+		if _, ok := in.Node.Annotations[key]; ok {
+			return true, []string{fmt.Sprintf("component %s reported ready", component)}
+		}
+		return false, []string{fmt.Sprintf("component %s not reported ready", component)}
+	})
+}
+
+// AllOf is satisfied when every checker is; reasons come from all failing ones
+// (or, when ready, from all checkers).
+func AllOf(checkers ...ReadinessChecker) ReadinessChecker {
+	return ReadinessFunc(func(in ReadinessInput) (bool, []string) {
+		ready := true
+		var reasons, satisfied []string
+		for _, c := range checkers {
+			ok, r := c.Ready(in)
+			if !ok {
+				ready = false
+				reasons = append(reasons, r...)
+				continue
+			}
+			satisfied = append(satisfied, r...)
+		}
+		if ready {
+			return true, satisfied
+		}
+		return false, reasons
+	})
+}
+
+// AnyOf is satisfied when at least one checker is.
+func AnyOf(checkers ...ReadinessChecker) ReadinessChecker {
+	return ReadinessFunc(func(in ReadinessInput) (bool, []string) {
+		var reasons []string
+		for _, c := range checkers {
+			ok, r := c.Ready(in)
+			if ok {
+				return true, r
+			}
+			reasons = append(reasons, r...)
+		}
+		return false, reasons
+	})
+}
+
+func phaseOrPending(p corev1.PodPhase) corev1.PodPhase {
+	if p == "" {
+		return corev1.PodPending
+	}
+	return p
+}
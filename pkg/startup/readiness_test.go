@@ -0,0 +1,91 @@
+package startup
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestReadinessCheckers(t *testing.T) {
+	node := makeNode("n1", StartupTaint)
+	node.Labels = map[string]string{"gpu.example.com/driver": "ready"}
+	node.Annotations = map[string]string{ComponentReadyAnnotation("prefetch"): "2024-01-01T00:00:00Z"}
+	node.Status.Conditions = []corev1.NodeCondition{{Type: "NetworkReady", Status: corev1.ConditionTrue}}
+
+	succeeded := podWith("job-1", "n1", labeledStartup(), nil, nil, nil)
+	succeeded.Status.Phase = corev1.PodSucceeded
+	succeeded.Status.InitContainerStatuses = []corev1.ContainerStatus{{
+		Name:  "install",
+		State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{ExitCode: 0}},
+	}}
+	failed := podWith("job-2", "n1", labeledStartup(), nil, []corev1.ContainerStatus{{
+		Name:  "install",
+		State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{ExitCode: 3}},
+	}}, nil)
+	failed.Status.Phase = corev1.PodFailed
+	gated := podWith("ds-1", "n1", labeledStartup(), map[string]string{"example.com/warm": "done"}, nil,
+		[]corev1.PodCondition{{Type: "example.com/CacheWarm", Status: corev1.ConditionTrue}})
+
+	cases := []struct {
+		name    string
+		checker ReadinessChecker
+		pods    []*corev1.Pod
+		want    bool
+	}{
+		{"default no pods", DefaultReadiness, nil, false},
+		{"podSucceeded", PodSucceeded(), []*corev1.Pod{succeeded}, true},
+		{"podSucceeded failed", PodSucceeded(), []*corev1.Pod{failed}, false},
+		{"exitZero init container", ContainerExitedZero("install"), []*corev1.Pod{succeeded}, true},
+		{"exitZero nonzero", ContainerExitedZero("install"), []*corev1.Pod{failed}, false},
+		{"exitZero missing", ContainerExitedZero("other"), []*corev1.Pod{succeeded}, false},
+		{"annotation", PodAnnotation("example.com/warm", "done"), []*corev1.Pod{gated}, true},
+		{"annotation wrong value", PodAnnotation("example.com/warm", "started"), []*corev1.Pod{gated}, false},
+		{"podCondition", PodConditionTrue("example.com/CacheWarm"), []*corev1.Pod{gated}, true},
+		{"nodeLabel", NodeLabel("gpu.example.com/driver", "ready"), nil, true},
+		{"nodeLabel mismatch", NodeLabel("gpu.example.com/driver", "installed"), nil, false},
+		{"nodeCondition", NodeCondition("NetworkReady", corev1.ConditionTrue), nil, true},
+		{"nodeCondition absent", NodeCondition("StorageReady", corev1.ConditionTrue), nil, false},
+		{"componentReady", ComponentReady("prefetch"), nil, true},
+		{"componentReady missing", ComponentReady("driver"), nil, false},
+		{"allOf", AllOf(PodSucceeded(), NodeLabel("gpu.example.com/driver", "ready")), []*corev1.Pod{succeeded}, true},
+		{"allOf one fails", AllOf(PodSucceeded(), NodeLabel("missing", "x")), []*corev1.Pod{succeeded}, false},
+		{"anyOf", AnyOf(PodReady(), PodSucceeded()), []*corev1.Pod{succeeded}, true},
+		{"anyOf none", AnyOf(PodReady(), PodSucceeded()), []*corev1.Pod{failed}, false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, reasons := tc.checker.Ready(ReadinessInput{Node: node, Pods: tc.pods})
+			if got != tc.want {
+				t.Fatalf("ready=%v want %v (reasons %v)", got, tc.want, reasons)
+			}
+			if !got && len(reasons) == 0 {
+				t.Fatalf("expected reasons when not ready")
+			}
+		})
+	}
+}
+
+func TestAllOf_FailingCheckerWithoutReasons(t *testing.T) {
+	silent := ReadinessFunc(func(ReadinessInput) (bool, []string) { return false, nil })
+	if ok, _ := AllOf(silent).Ready(ReadinessInput{Node: makeNode("n1")}); ok {
+		t.Fatalf("expected not ready")
+	}
+}
+
+func TestReadiness_SatisfiedBy(t *testing.T) {
+	node := makeNode("n1", StartupTaint)
+	node.Labels = map[string]string{"gpu.example.com/driver": "ready"}
+	succeeded := podWith("job-1", "n1", labeledStartup(), nil, nil, nil)
+	succeeded.Status.Phase = corev1.PodSucceeded
+	in := ReadinessInput{Node: node, Pods: []*corev1.Pod{succeeded}}
+
+	ok, got := AllOf(PodSucceeded(), NodeLabel("gpu.example.com/driver", "ready")).Ready(in)
+	want := []string{"default/job-1: phase Succeeded", "node label gpu.example.com/driver=ready"}
+	if !ok || len(got) != 2 || got[0] != want[0] || got[1] != want[1] {
+		t.Fatalf("allOf: ready=%v satisfiedBy=%q want %q", ok, got, want)
+	}
+	ok, got = AnyOf(NodeLabel("missing", "x"), NodeLabel("gpu.example.com/driver", "ready")).Ready(in)
+	if !ok || len(got) != 1 || got[0] != want[1] {
+		t.Fatalf("anyOf: ready=%v satisfiedBy=%q want %q", ok, got, want[1:])
+	}
+}
@@ -0,0 +1,52 @@
+package startup
+
+import (
+	"strconv"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// CompletionStale reports whether the node carries a completion annotation left
+// over from a previous Node object with the same name (deleted and re-registered
+// by the kubelet). Such a node must be gated again.
+//
+// The webhook and the controller both use this so they agree:
+//   - admission CREATE: the object has no UID yet, so any completion is stale;
+//   - completedUID recorded: stale unless it matches the node's UID;
+//   - legacy annotation without UID: stale if written before creationTimestamp.
+func CompletionStale(node *corev1.Node) bool {
+	completed := node.Annotations[NodeStartupCompletedAnnotation]
+	if completed == "" {
+		return false
+	}
+	if node.UID == "" {
+		return true
+	}
+	if uid := node.Annotations[NodeStartupCompletedUIDAnnotation]; uid != "" {
+		return uid != string(node.UID)
+	}
+	at, err := parseCompletedAt(completed)
+	if err != nil || node.CreationTimestamp.IsZero() {
+		return false
+	}
+	return at.Before(node.CreationTimestamp.Time)
+}
+
+// completionRecorded reports a completion annotation that belongs to this Node object.
+func completionRecorded(node *corev1.Node) bool {
+	return node.Annotations[NodeStartupCompletedAnnotation] != "" && !CompletionStale(node)
+}
+
+// parseCompletedAt reads completedAt as RFC3339, or as the unix epoch seconds
+// written by older releases.
+func parseCompletedAt(v string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+	sec, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Unix(sec, 0), nil
+}
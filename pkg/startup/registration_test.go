@@ -0,0 +1,196 @@
+package startup
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func persistedNode(name string, uid types.UID, created time.Time, annotations map[string]string, taints ...corev1.Taint) *corev1.Node {
+	n := makeNode(name, taints...)
+	n.UID = uid
+	n.CreationTimestamp = metav1.NewTime(created)
+	n.Annotations = annotations
+	return n
+}
+
+func TestCompletionStale(t *testing.T) {
+	created := time.Now().Add(-time.Hour).Truncate(time.Second)
+	epoch := func(t time.Time) string { return strconv.FormatInt(t.Unix(), 10) }
+	rfc := func(t time.Time) string { return t.UTC().Format(time.RFC3339) }
+	cases := []struct {
+		name string
+		node *corev1.Node
+		want bool
+	}{
+		{"no annotation", persistedNode("n", "u1", created, nil), false},
+		{"admission create (no uid)", persistedNode("n", "", time.Time{}, map[string]string{
+			NodeStartupCompletedAnnotation: epoch(time.Now()),
+		}), true},
+		{"uid matches", persistedNode("n", "u1", created, map[string]string{
+			NodeStartupCompletedAnnotation:    epoch(created.Add(time.Minute)),
+			NodeStartupCompletedUIDAnnotation: "u1",
+		}), false},
+		{"uid from previous object", persistedNode("n", "u2", created, map[string]string{
+			NodeStartupCompletedAnnotation:    epoch(created.Add(time.Minute)),
+			NodeStartupCompletedUIDAnnotation: "u1",
+		}), true},
+		{"legacy completed after creation", persistedNode("n", "u1", created, map[string]string{
+			NodeStartupCompletedAnnotation: epoch(created.Add(time.Minute)),
+		}), false},
+		{"legacy completed before creation", persistedNode("n", "u1", created, map[string]string{
+			NodeStartupCompletedAnnotation: epoch(created.Add(-time.Minute)),
+		}), true},
+		{"rfc3339 completed after creation", persistedNode("n", "u1", created, map[string]string{
+			NodeStartupCompletedAnnotation: rfc(created.Add(time.Minute)),
+		}), false},
+		{"rfc3339 completed before creation", persistedNode("n", "u1", created, map[string]string{
+			NodeStartupCompletedAnnotation: rfc(created.Add(-time.Minute)),
+		}), true},
+		{"legacy unparsable", persistedNode("n", "u1", created, map[string]string{
+			NodeStartupCompletedAnnotation: "garbage",
+		}), false},
+	}
+	for _, tc := range cases {
+		if got := CompletionStale(tc.node); got != tc.want {
+			t.Errorf("%s: CompletionStale=%v want %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestSyncNode_RegatesStaleCompletion(t *testing.T) {
+	created := time.Now().Add(-time.Minute)
+	n := persistedNode("n1", "new-uid", created, map[string]string{
+		NodeStartupCompletedAnnotation:    strconv.FormatInt(created.Add(-time.Hour).Unix(), 10),
+		NodeStartupCompletedUIDAnnotation: "old-uid",
+	})
+	c, client := newControllerWith(n)
+	if err := c.syncNode(n); err != nil {
+		t.Fatalf("sync: %v", err)
+	}
+	got, _ := client.CoreV1().Nodes().Get(ctx(), "n1", metav1.GetOptions{})
+	if !HasStartupTaint(got) {
+		t.Fatalf("expected re-registered node to be re-gated")
+	}
+	if _, ok := got.Annotations[NodeStartupCompletedAnnotation]; ok {
+		t.Fatalf("stale completion annotation should be removed")
+	}
+}
+
+func TestSyncNode_StaleCompletionWithWorkloadsNotRegated(t *testing.T) {
+	n := persistedNode("n1", "new-uid", time.Now(), map[string]string{
+		NodeStartupCompletedAnnotation:    "1",
+		NodeStartupCompletedUIDAnnotation: "old-uid",
+	})
+	work := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "user", Namespace: "default"},
+		Spec:       corev1.PodSpec{NodeName: "n1"},
+	}
+	c, client := newControllerWith(n, work)
+	if err := c.syncNode(n); err != nil {
+		t.Fatalf("sync: %v", err)
+	}
+	got, _ := client.CoreV1().Nodes().Get(ctx(), "n1", metav1.GetOptions{})
+	if HasStartupTaint(got) {
+		t.Fatalf("busy node must not be re-gated")
+	}
+}
+
+func TestSyncNode_ValidCompletionNotRegated(t *testing.T) {
+	n := persistedNode("n1", "u1", time.Now().Add(-time.Hour), map[string]string{
+		NodeStartupCompletedAnnotation:    strconv.FormatInt(time.Now().Unix(), 10),
+		NodeStartupCompletedUIDAnnotation: "u1",
+	})
+	c, client := newControllerWith(n)
+	if err := c.syncNode(n); err != nil {
+		t.Fatalf("sync: %v", err)
+	}
+	got, _ := client.CoreV1().Nodes().Get(ctx(), "n1", metav1.GetOptions{})
+	if HasStartupTaint(got) {
+		t.Fatalf("completed node must not be re-gated")
+	}
+}
+
+func TestHandleNodeUpdate_RegatesDroppedTaint(t *testing.T) {
+	gated := persistedNode("n1", "u1", time.Now(), nil, StartupTaint)
+	reset := persistedNode("n1", "u1", time.Now(), nil) // kubelet reset taints
+	c, client := newControllerWith(reset)
+	c.handleNodeUpdate(gated, reset)
+	got, _ := client.CoreV1().Nodes().Get(ctx(), "n1", metav1.GetOptions{})
+	if !HasStartupTaint(got) {
+		t.Fatalf("expected startup taint re-applied after it was dropped mid-gating")
+	}
+}
+
+func TestHandleNodeUpdate_DroppedTaintWithWorkloadsNotRegated(t *testing.T) {
+	gated := persistedNode("n1", "u1", time.Now(), nil, StartupTaint)
+	untainted := persistedNode("n1", "u1", time.Now(), nil) // e.g. kubectl taint node n1 key-
+	app := podWith("app", "n1", nil, nil, nil, nil)
+	c, client := newControllerWith(untainted, app)
+	c.handleNodeUpdate(gated, untainted)
+	got, _ := client.CoreV1().Nodes().Get(ctx(), "n1", metav1.GetOptions{})
+	if HasStartupTaint(got) {
+		t.Fatalf("node already running workloads must not be re-gated")
+	}
+}
+
+func TestHandleNodeUpdate_DroppedTaintWithInitPodRegated(t *testing.T) {
+	gated := persistedNode("n1", "u1", time.Now(), nil, StartupTaint)
+	reset := persistedNode("n1", "u1", time.Now(), nil)
+	initPod := podWith("init", "n1", labeledStartup(), nil, nil, nil)
+	c, client := newControllerWith(reset, initPod)
+	c.handleNodeUpdate(gated, reset)
+	got, _ := client.CoreV1().Nodes().Get(ctx(), "n1", metav1.GetOptions{})
+	if !HasStartupTaint(got) {
+		t.Fatalf("an init pod is not a workload; expected the taint re-applied")
+	}
+}
+
+func TestHandleNodeUpdate_ControllerRemovalNotRegated(t *testing.T) {
+	n := persistedNode("n1", "u1", time.Now(), nil, StartupTaint)
+	p := podWith("init", "n1", labeledStartup(), map[string]string{StartPodReadyAnnotation: "true"}, nil, nil)
+	c, client := newControllerWith(n, p)
+	if err := c.removeStartupTaint(n, ""); err != nil {
+		t.Fatalf("remove: %v", err)
+	}
+	released, _ := client.CoreV1().Nodes().Get(ctx(), "n1", metav1.GetOptions{})
+	if released.Annotations[NodeStartupCompletedUIDAnnotation] != "u1" {
+		t.Fatalf("completion UID not recorded: %v", released.Annotations)
+	}
+	c.handleNodeUpdate(n, released)
+	got, _ := client.CoreV1().Nodes().Get(ctx(), "n1", metav1.GetOptions{})
+	if HasStartupTaint(got) {
+		t.Fatalf("controller's own removal must not trigger re-gate")
+	}
+}
+
+func TestRemoveStartupTaint_RecordsAudit(t *testing.T) {
+	created := time.Now().Add(-10 * time.Minute)
+	n := persistedNode("n1", "u1", created, map[string]string{
+		NodeTaintedAtAnnotation: created.Add(2 * time.Minute).UTC().Format(time.RFC3339),
+	}, StartupTaint)
+	p := podWith("init", "n1", labeledStartup(), map[string]string{StartPodReadyAnnotation: "true"}, nil, nil)
+	c, client := newControllerWith(n, p)
+	if err := c.syncNode(n); err != nil {
+		t.Fatalf("sync: %v", err)
+	}
+	got, _ := client.CoreV1().Nodes().Get(ctx(), "n1", metav1.GetOptions{})
+	a := got.Annotations
+	if _, err := time.Parse(time.RFC3339, a[NodeStartupCompletedAnnotation]); err != nil {
+		t.Fatalf("completedAt %q not RFC3339: %v", a[NodeStartupCompletedAnnotation], err)
+	}
+	if d, err := time.ParseDuration(a[NodeStartupDurationAnnotation]); err != nil || d < 10*time.Minute-time.Second || d > 11*time.Minute {
+		t.Fatalf("startupDuration=%q", a[NodeStartupDurationAnnotation])
+	}
+	if d, err := time.ParseDuration(a[NodeGatingDurationAnnotation]); err != nil || d < 8*time.Minute-time.Second || d > 9*time.Minute {
+		t.Fatalf("gatingDuration=%q", a[NodeGatingDurationAnnotation])
+	}
+	want := "policy default: default/init: annotation " + StartPodReadyAnnotation + "=true"
+	if a[NodeSatisfiedByAnnotation] != want {
+		t.Fatalf("satisfiedBy=%q want %q", a[NodeSatisfiedByAnnotation], want)
+	}
+}
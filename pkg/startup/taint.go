@@ -0,0 +1,78 @@
+package startup
+
+import (
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// Autoscaler conventions for taints that only exist while a node warms up.
+const (
+	// Cluster Autoscaler ignores taints with this prefix when deciding whether a
+	// new node is usable, so gated nodes do not trigger further scale-ups.
+	ClusterAutoscalerIgnoreTaintPrefix = "ignore-taint.cluster-autoscaler.kubernetes.io/"
+	// Newer Cluster Autoscaler releases prefer this prefix for the same purpose.
+	ClusterAutoscalerStartupTaintPrefix = "startup-taint.cluster-autoscaler.kubernetes.io/"
+
+	// Karpenter taints nodes it launched until it has registered them with their NodeClaim.
+	KarpenterUnregisteredTaintKey = "karpenter.sh/unregistered"
+)
+
+// TaintValueMatch controls which values of the startup taint key count as the startup taint.
+type TaintValueMatch string
+
+const (
+	// TaintValueAny matches the key alone, so a drifted value (wait -> warming) still gates.
+	TaintValueAny TaintValueMatch = "any"
+	// TaintValueExact matches only StartupTaint.Value.
+	TaintValueExact TaintValueMatch = "exact"
+)
+
+var taintValueMatch = TaintValueAny
+
+// SetTaintValueMatch sets how taint values are matched (default TaintValueAny).
+func SetTaintValueMatch(m TaintValueMatch) error {
+	switch m {
+	case TaintValueAny, TaintValueExact:
+		taintValueMatch = m
+		return nil
+	}
+	return fmt.Errorf("invalid taint value match %q (want %s or %s)", m, TaintValueAny, TaintValueExact)
+}
+
+// SetTaintKey changes the key of the startup taint (default TaintKey), e.g. to an
+// ignore-taint.cluster-autoscaler.kubernetes.io/ key. Call it before starting the
+// controller or webhook.
+func SetTaintKey(key string) error {
+	if errs := validation.IsQualifiedName(key); len(errs) > 0 {
+		return fmt.Errorf("invalid taint key %q: %s", key, strings.Join(errs, "; "))
+	}
+	StartupTaint.Key = key
+	return nil
+}
+
+// isStartupTaint reports whether t is the startup taint: the key, with any effect
+// (policies choose the effect, see Policy.TaintEffect) and a value allowed by
+// SetTaintValueMatch.
+func isStartupTaint(t corev1.Taint) bool {
+	return t.Key == StartupTaint.Key && (taintValueMatch == TaintValueAny || t.Value == StartupTaint.Value)
+}
+
+// TaintFor returns the startup taint the node's policy applies.
+func (c *Controller) TaintFor(node *corev1.Node) corev1.Taint {
+	return c.PolicyFor(node).Taint()
+}
+
+// KarpenterUnregistered reports whether Karpenter has not yet registered the node.
+// Karpenter syncs NodeClaim taints (including startupTaints) at registration, so
+// the startup taint is not removed before then.
+func KarpenterUnregistered(node *corev1.Node) bool {
+	for _, t := range node.Spec.Taints {
+		if t.Key == KarpenterUnregisteredTaintKey {
+			return true
+		}
+	}
+	return false
+}
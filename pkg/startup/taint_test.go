@@ -0,0 +1,88 @@
+package startup
+
+import (
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestSetTaintKey(t *testing.T) {
+	t.Cleanup(func() { StartupTaint.Key = TaintKey })
+	if err := SetTaintKey("not a key!"); err == nil {
+		t.Fatal("expected invalid key error")
+	}
+	key := ClusterAutoscalerIgnoreTaintPrefix + "startup"
+	if err := SetTaintKey(key); err != nil {
+		t.Fatal(err)
+	}
+	n := makeNode("n1", corev1.Taint{Key: key, Value: TaintValue, Effect: corev1.TaintEffectNoSchedule})
+	if !HasStartupTaint(n) {
+		t.Fatal("custom key not recognised")
+	}
+	if HasStartupTaint(makeNode("n2", corev1.Taint{Key: TaintKey, Value: TaintValue, Effect: corev1.TaintEffectNoSchedule})) {
+		t.Fatal("default key should no longer match")
+	}
+	if !releaseNode(n) || len(n.Spec.Taints) != 0 {
+		t.Fatalf("custom key not removed: %v", n.Spec.Taints)
+	}
+}
+
+func TestSyncNode_WaitsForKarpenterRegistration(t *testing.T) {
+	unregistered := corev1.Taint{Key: KarpenterUnregisteredTaintKey, Effect: corev1.TaintEffectNoExecute}
+	node := makeNode("n1", unregistered, StartupTaint)
+	c, client := newControllerWith(node, podWith("init", "n1", labeledStartup(), map[string]string{StartPodReadyAnnotation: "true"}, nil, nil))
+	c.synced.Store(true)
+
+	if err := c.syncNode(node); err != nil {
+		t.Fatal(err)
+	}
+	got, _ := client.CoreV1().Nodes().Get(ctx(), "n1", metav1.GetOptions{})
+	if !HasStartupTaint(got) {
+		t.Fatal("startup taint removed before Karpenter registered the node")
+	}
+	if pending, _ := c.PendingComponents(got); len(pending) != 1 || !strings.Contains(pending[0], KarpenterUnregisteredTaintKey) {
+		t.Fatalf("unexpected pending: %v", pending)
+	}
+
+	got.Spec.Taints = []corev1.Taint{StartupTaint}
+	got, _ = client.CoreV1().Nodes().Update(ctx(), got, metav1.UpdateOptions{})
+	if err := c.syncNode(got); err != nil {
+		t.Fatal(err)
+	}
+	got, _ = client.CoreV1().Nodes().Get(ctx(), "n1", metav1.GetOptions{})
+	if HasStartupTaint(got) {
+		t.Fatal("startup taint should be removed once registered")
+	}
+}
+
+func TestTaintValueMatch(t *testing.T) {
+	t.Cleanup(func() { _ = SetTaintValueMatch(TaintValueAny) })
+	drifted := makeNode("n1",
+		corev1.Taint{Key: "foo", Effect: corev1.TaintEffectNoSchedule},
+		corev1.Taint{Key: TaintKey, Value: "warming", Effect: corev1.TaintEffectNoSchedule},
+		corev1.Taint{Key: TaintKey, Value: TaintValue, Effect: corev1.TaintEffectNoExecute},
+	)
+	if !HasStartupTaint(drifted) {
+		t.Fatal("drifted value should match by key")
+	}
+	released := drifted.DeepCopy()
+	if !releaseNode(released) || len(released.Spec.Taints) != 1 || released.Spec.Taints[0].Key != "foo" {
+		t.Fatalf("every startup-key taint should be removed: %v", released.Spec.Taints)
+	}
+
+	if err := SetTaintValueMatch("loose"); err == nil {
+		t.Fatal("expected invalid mode error")
+	}
+	if err := SetTaintValueMatch(TaintValueExact); err != nil {
+		t.Fatal(err)
+	}
+	if HasStartupTaint(makeNode("n2", corev1.Taint{Key: TaintKey, Value: "warming", Effect: corev1.TaintEffectNoSchedule})) {
+		t.Fatal("exact mode should ignore other values")
+	}
+	exact := drifted.DeepCopy()
+	if !releaseNode(exact) || len(exact.Spec.Taints) != 2 || exact.Spec.Taints[1].Value != "warming" {
+		t.Fatalf("exact mode should only remove the exact value: %v", exact.Spec.Taints)
+	}
+}
@@ -0,0 +1,121 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"runtime/debug"
+	"time"
+
+	"k8s.io/klog/v2"
+)
+
+const (
+	// DefaultMaxBodyBytes fits an AdmissionReview carrying two objects at the
+	// apiserver's 1.5MiB request limit.
+	DefaultMaxBodyBytes = 3 << 20
+	// DefaultRequestTimeout stays under the webhooks' timeoutSeconds (5s in deploy/).
+	DefaultRequestTimeout = 4 * time.Second
+)
+
+// Limits bound one admission request; zero fields take the defaults.
+type Limits struct {
+	MaxBodyBytes int64
+	// Timeout is the per-request deadline; keep it under the webhook's timeoutSeconds
+	// so the answer is ours, not the apiserver's failurePolicy.
+	Timeout time.Duration
+}
+
+func (l Limits) withDefaults() Limits {
+	if l.MaxBodyBytes <= 0 {
+		l.MaxBodyBytes = DefaultMaxBodyBytes
+	}
+	if l.Timeout <= 0 {
+		l.Timeout = DefaultRequestTimeout
+	}
+	return l
+}
+
+// admissionHandler wraps an admission handler: only POST application/json is
+// accepted, the body is capped at l.MaxBodyBytes, and the handler runs under a
+// l.Timeout deadline. Oversized bodies, timeouts and panics are answered with an
+// AdmissionReview per onError.
+func admissionHandler(h http.HandlerFunc, onError ErrorPolicy, l Limits) http.Handler {
+	l = l.withDefaults()
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if mt, _, err := mime.ParseMediaType(r.Header.Get("Content-Type")); err != nil || mt != "application/json" {
+			http.Error(w, "content type must be application/json", http.StatusUnsupportedMediaType)
+			return
+		}
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, l.MaxBodyBytes))
+		if err != nil {
+			code := int32(http.StatusBadRequest)
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				code = http.StatusRequestEntityTooLarge
+			}
+			writeError(w, partialReview(body, ""), onError, errReadBody, code, err)
+			return
+		}
+		review, _ := readReview(body)
+
+		ctx, cancel := context.WithTimeout(r.Context(), l.Timeout)
+		defer cancel()
+		r = r.WithContext(ctx)
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		// The handler writes into a buffer so a late answer cannot race the
+		// timeout one.
+		buf := &bufferedWriter{header: http.Header{}}
+		done := make(chan interface{}, 1)
+		go func() {
+			defer func() {
+				if p := recover(); p != nil {
+					klog.Errorf("panic serving %s: %v\n%s", r.URL.Path, p, debug.Stack())
+					done <- p
+				}
+			}()
+			h(buf, r)
+			done <- nil
+		}()
+		select {
+		case p := <-done:
+			if p != nil {
+				writeError(w, review, onError, errPanic, http.StatusInternalServerError, fmt.Errorf("%v", p))
+				return
+			}
+			buf.flush(w)
+		case <-ctx.Done():
+			writeError(w, review, onError, errTimeout, http.StatusGatewayTimeout, fmt.Errorf("no answer within %s", l.Timeout))
+		}
+	})
+}
+
+type bufferedWriter struct {
+	header http.Header
+	body   bytes.Buffer
+	code   int
+}
+
+func (b *bufferedWriter) Header() http.Header         { return b.header }
+func (b *bufferedWriter) Write(p []byte) (int, error) { return b.body.Write(p) }
+func (b *bufferedWriter) WriteHeader(code int)        { b.code = code }
+
+func (b *bufferedWriter) flush(w http.ResponseWriter) {
+	for k, v := range b.header {
+		w.Header()[k] = v
+	}
+	if b.code != 0 {
+		w.WriteHeader(b.code)
+	}
+	w.Write(b.body.Bytes())
+}
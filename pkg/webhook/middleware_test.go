@@ -0,0 +1,95 @@
+package webhook
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	startup "github.com/zhangchl007/nodetaintshandler/pkg/startup"
+)
+
+func serveAdmission(h http.Handler, method, contentType string, body []byte) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, "/mutate-node", bytes.NewReader(body))
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, req)
+	return rr
+}
+
+func TestAdmissionHandler_Mutates(t *testing.T) {
+	mux := http.NewServeMux()
+	RegisterMutator(mux, &NodeMutator{})
+	node := &corev1.Node{ObjectMeta: v1.ObjectMeta{Name: "n1"}}
+	rr := serveAdmission(mux, http.MethodPost, "application/json; charset=utf-8", buildAdmissionReview(node, admissionv1.Create, "Node"))
+	if rr.Code != http.StatusOK || rr.Header().Get("Content-Type") != "application/json" {
+		t.Fatalf("status %d, content type %q", rr.Code, rr.Header().Get("Content-Type"))
+	}
+	if !startup.HasStartupTaint(patchedNode(t, node, decodeReview(t, rr))) {
+		t.Fatalf("expected startup taint through the wrapped handler")
+	}
+}
+
+func TestAdmissionHandler_RejectsMethodAndContentType(t *testing.T) {
+	h := admissionHandler(MutateNode, ErrorPolicyAllow, Limits{})
+	body := buildAdmissionReview(&corev1.Node{}, admissionv1.Create, "Node")
+	if rr := serveAdmission(h, http.MethodGet, "application/json", nil); rr.Code != http.StatusMethodNotAllowed || rr.Header().Get("Allow") != http.MethodPost {
+		t.Fatalf("GET: status %d, Allow %q", rr.Code, rr.Header().Get("Allow"))
+	}
+	for _, ct := range []string{"", "text/plain", "application/yaml"} {
+		if rr := serveAdmission(h, http.MethodPost, ct, body); rr.Code != http.StatusUnsupportedMediaType {
+			t.Fatalf("content type %q: status %d", ct, rr.Code)
+		}
+	}
+}
+
+func TestAdmissionHandler_BodyTooLarge(t *testing.T) {
+	h := admissionHandler(MutateNode, ErrorPolicyDeny, Limits{MaxBodyBytes: 64})
+	node := &corev1.Node{ObjectMeta: v1.ObjectMeta{Name: strings.Repeat("n", 100)}}
+	rr := serveAdmission(h, http.MethodPost, "application/json", buildAdmissionReview(node, admissionv1.Create, "Node"))
+	resp := decodeReview(t, rr).Response
+	if resp == nil || resp.Allowed || resp.Result == nil || resp.Result.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("expected 413 denial, got %+v", resp)
+	}
+	if resp.AuditAnnotations["error"] != errReadBody {
+		t.Fatalf("audit annotations %v", resp.AuditAnnotations)
+	}
+}
+
+func TestAdmissionHandler_RecoversPanic(t *testing.T) {
+	h := admissionHandler(func(http.ResponseWriter, *http.Request) { panic("boom") }, ErrorPolicyAllow, Limits{})
+	rr := serveAdmission(h, http.MethodPost, "application/json", buildAdmissionReview(&corev1.Node{}, admissionv1.Create, "Node"))
+	resp := decodeReview(t, rr).Response
+	if resp == nil || !resp.Allowed || resp.UID != "uid-123" {
+		t.Fatalf("expected allow for uid-123 after panic, got %+v", resp)
+	}
+	if resp.Result == nil || resp.Result.Code != http.StatusInternalServerError || resp.AuditAnnotations["error"] != errPanic {
+		t.Fatalf("unexpected result %+v, annotations %v", resp.Result, resp.AuditAnnotations)
+	}
+}
+
+func TestAdmissionHandler_Timeout(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	slow := func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		MutateNode(w, r)
+	}
+	h := admissionHandler(slow, ErrorPolicyDeny, Limits{Timeout: 20 * time.Millisecond})
+	rr := serveAdmission(h, http.MethodPost, "application/json", buildAdmissionReview(&corev1.Node{}, admissionv1.Create, "Node"))
+	resp := decodeReview(t, rr).Response
+	if resp == nil || resp.Allowed || resp.UID != "uid-123" {
+		t.Fatalf("expected denial for uid-123 on timeout, got %+v", resp)
+	}
+	if resp.Result == nil || resp.Result.Code != http.StatusGatewayTimeout || resp.Result.Reason != v1.StatusReasonTimeout {
+		t.Fatalf("unexpected result %+v", resp.Result)
+	}
+}
@@ -0,0 +1,139 @@
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	corev1 "k8s.io/api/core/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+
+	startup "github.com/zhangchl007/nodetaintshandler/pkg/startup"
+)
+
+// GatingStatus reports what still holds a node's startup taint (startup.Controller implements it).
+type GatingStatus interface {
+	PendingComponents(node *corev1.Node) ([]string, error)
+}
+
+// TaintGuard validates Node UPDATEs: while init work is pending, only allowlisted
+// identities may remove the startup taint.
+type TaintGuard struct {
+	Status GatingStatus
+	// Allowed holds usernames (e.g. the controller's service account) and
+	// "group:<name>" entries permitted to remove the taint at any time.
+	Allowed []string
+	// OnError answers reviews that cannot be processed (default ErrorPolicyAllow).
+	OnError ErrorPolicy
+	// Limits bound each request served through RegisterTaintGuard.
+	Limits Limits
+}
+
+// ValidateNode denies early removal of the startup taint by non-allowlisted callers.
+func (g *TaintGuard) ValidateNode(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, admissionv1.AdmissionReview{}, g.OnError, errReadBody, http.StatusBadRequest, err)
+		return
+	}
+	review, err := readReview(body)
+	if err != nil {
+		writeError(w, review, g.OnError, errDecodeReview, http.StatusBadRequest, err)
+		return
+	}
+	req := review.Request
+	if req == nil || req.Kind.Kind != "Node" || req.Operation != admissionv1.Update {
+		writeResponse(w, review, nil)
+		return
+	}
+
+	oldNode, newNode := &corev1.Node{}, &corev1.Node{}
+	if err := json.Unmarshal(req.OldObject.Raw, oldNode); err != nil {
+		writeError(w, review, g.OnError, errDecodeObject, http.StatusBadRequest, fmt.Errorf("decode old Node: %w", err))
+		return
+	}
+	if err := json.Unmarshal(req.Object.Raw, newNode); err != nil {
+		writeError(w, review, g.OnError, errDecodeObject, http.StatusBadRequest, fmt.Errorf("decode Node: %w", err))
+		return
+	}
+	if !startup.HasStartupTaint(oldNode) || startup.HasStartupTaint(newNode) {
+		writeResponse(w, review, nil)
+		return
+	}
+	if g.allowed(req.UserInfo) {
+		klog.Infof("Startup taint removal on node %s by allowlisted %s", newNode.Name, req.UserInfo.Username)
+		writeResponse(w, review, nil)
+		return
+	}
+
+	// Only an override already on the node counts: setting skip or release in the
+	// update that drops the taint would let any nodes/update caller bypass the guard.
+	switch ov := startup.NodeOverride(oldNode); {
+	case ov.Ungated() && startup.NodeOverride(newNode) != startup.OverrideHold:
+		klog.Infof("Startup taint removal on node %s by %s allowed: %s=true", newNode.Name, req.UserInfo.Username, ov.Annotation())
+		writeResponse(w, review, nil)
+		return
+	case ov == startup.OverrideHold, startup.NodeOverride(newNode) == startup.OverrideHold:
+		msg := fmt.Sprintf("%s may not remove taint %s from node %s: node is held (%s=true)",
+			req.UserInfo.Username, startup.StartupTaint.Key, oldNode.Name, startup.HoldAnnotation)
+		klog.Infof("Denied: %s", msg)
+		writeDenied(w, review, msg)
+		return
+	}
+
+	pending, err := g.Status.PendingComponents(oldNode)
+	if err != nil {
+		// Fail open: an unknown gating state should not wedge node updates.
+		klog.Warningf("gating status for node %s: %v; allowing taint removal by %s", oldNode.Name, err, req.UserInfo.Username)
+		writeResponse(w, review, nil)
+		return
+	}
+	if len(pending) == 0 {
+		writeResponse(w, review, nil)
+		return
+	}
+	msg := fmt.Sprintf("%s may not remove taint %s from node %s while startup is gating; pending init components: %s",
+		req.UserInfo.Username, startup.StartupTaint.Key, oldNode.Name, strings.Join(pending, "; "))
+	klog.Infof("Denied: %s", msg)
+	writeDenied(w, review, msg)
+}
+
+func (g *TaintGuard) allowed(u authenticationv1.UserInfo) bool {
+	for _, a := range g.Allowed {
+		if group, ok := strings.CutPrefix(a, "group:"); ok {
+			for _, ug := range u.Groups {
+				if ug == group {
+					return true
+				}
+			}
+			continue
+		}
+		if a == u.Username {
+			return true
+		}
+	}
+	return false
+}
+
+func writeDenied(w http.ResponseWriter, in admissionv1.AdmissionReview, msg string) {
+	writeReview(w, in, &admissionv1.AdmissionResponse{
+		Allowed: false,
+		Result: &v1.Status{
+			Status:  v1.StatusFailure,
+			Code:    http.StatusForbidden,
+			Reason:  v1.StatusReasonForbidden,
+			Message: msg,
+		},
+	})
+}
+
+// RegisterTaintGuard registers the Node UPDATE validation handler on a mux.
+func RegisterTaintGuard(mux *http.ServeMux, g *TaintGuard) {
+	mux.Handle("/validate-node", admissionHandler(g.ValidateNode, g.OnError, g.Limits))
+	klog.Info("Webhook handler registered (/validate-node)")
+}
@@ -0,0 +1,197 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	corev1 "k8s.io/api/core/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	startup "github.com/zhangchl007/nodetaintshandler/pkg/startup"
+)
+
+const controllerSA = "system:serviceaccount:kube-system:nodetaintshandler"
+
+type fakeStatus struct {
+	pending []string
+	err     error
+}
+
+func (f fakeStatus) PendingComponents(*corev1.Node) ([]string, error) { return f.pending, f.err }
+
+func updateReview(oldNode, newNode *corev1.Node, user authenticationv1.UserInfo) []byte {
+	oldRaw, _ := json.Marshal(oldNode)
+	newRaw, _ := json.Marshal(newNode)
+	review := admissionv1.AdmissionReview{
+		Request: &admissionv1.AdmissionRequest{
+			UID:       "uid-upd",
+			Kind:      v1.GroupVersionKind{Kind: "Node"},
+			Operation: admissionv1.Update,
+			UserInfo:  user,
+			Object:    runtimeRaw(newRaw),
+			OldObject: runtimeRaw(oldRaw),
+		},
+	}
+	b, _ := json.Marshal(review)
+	return b
+}
+
+func validate(g *TaintGuard, body []byte) admissionv1.AdmissionReview {
+	req := httptest.NewRequest(http.MethodPost, "/validate-node", bytes.NewReader(body))
+	rr := httptest.NewRecorder()
+	g.ValidateNode(rr, req)
+	var out admissionv1.AdmissionReview
+	_ = json.Unmarshal(rr.Body.Bytes(), &out)
+	return out
+}
+
+func taintedNode() *corev1.Node {
+	return &corev1.Node{
+		ObjectMeta: v1.ObjectMeta{Name: "n1"},
+		Spec:       corev1.NodeSpec{Taints: []corev1.Taint{startup.StartupTaint}},
+	}
+}
+
+func untaintedNode() *corev1.Node {
+	return &corev1.Node{ObjectMeta: v1.ObjectMeta{Name: "n1"}}
+}
+
+func TestValidateNode_DeniesEarlyRemovalNamingPending(t *testing.T) {
+	g := &TaintGuard{Status: fakeStatus{pending: []string{"kube-system/init-abc: container init not ready"}}, Allowed: []string{controllerSA}}
+	ar := validate(g, updateReview(taintedNode(), untaintedNode(), authenticationv1.UserInfo{Username: "alice"}))
+	if ar.Response == nil || ar.Response.Allowed {
+		t.Fatalf("expected denial, got %+v", ar.Response)
+	}
+	if ar.Response.UID != "uid-upd" {
+		t.Fatalf("uid not echoed: %q", ar.Response.UID)
+	}
+	msg := ar.Response.Result.Message
+	if ar.Response.Result.Code != http.StatusForbidden || !strings.Contains(msg, "kube-system/init-abc") || !strings.Contains(msg, "alice") {
+		t.Fatalf("unexpected result %+v", ar.Response.Result)
+	}
+}
+
+func TestValidateNode_AllowsControllerServiceAccount(t *testing.T) {
+	g := &TaintGuard{Status: fakeStatus{pending: []string{"x"}}, Allowed: []string{controllerSA}}
+	ar := validate(g, updateReview(taintedNode(), untaintedNode(), authenticationv1.UserInfo{Username: controllerSA}))
+	if !ar.Response.Allowed {
+		t.Fatalf("controller SA should be allowed")
+	}
+}
+
+func TestValidateNode_AllowsAllowlistedGroup(t *testing.T) {
+	g := &TaintGuard{Status: fakeStatus{pending: []string{"x"}}, Allowed: []string{"group:platform-admins"}}
+	user := authenticationv1.UserInfo{Username: "bob", Groups: []string{"system:authenticated", "platform-admins"}}
+	if ar := validate(g, updateReview(taintedNode(), untaintedNode(), user)); !ar.Response.Allowed {
+		t.Fatalf("allowlisted group should be allowed")
+	}
+}
+
+func TestValidateNode_AllowsWhenNothingPending(t *testing.T) {
+	g := &TaintGuard{Status: fakeStatus{}}
+	if ar := validate(g, updateReview(taintedNode(), untaintedNode(), authenticationv1.UserInfo{Username: "alice"})); !ar.Response.Allowed {
+		t.Fatalf("removal after init completed should be allowed")
+	}
+}
+
+func TestValidateNode_AllowsUnrelatedUpdates(t *testing.T) {
+	g := &TaintGuard{Status: fakeStatus{pending: []string{"x"}}}
+	user := authenticationv1.UserInfo{Username: "system:node:n1"}
+	relabeled := taintedNode()
+	relabeled.Labels = map[string]string{"foo": "bar"}
+	if ar := validate(g, updateReview(taintedNode(), relabeled, user)); !ar.Response.Allowed {
+		t.Fatalf("update keeping the taint should be allowed")
+	}
+	if ar := validate(g, updateReview(untaintedNode(), untaintedNode(), user)); !ar.Response.Allowed {
+		t.Fatalf("update of ungated node should be allowed")
+	}
+}
+
+func TestValidateNode_FailsOpenOnStatusError(t *testing.T) {
+	g := &TaintGuard{Status: fakeStatus{err: errors.New("boom")}}
+	if ar := validate(g, updateReview(taintedNode(), untaintedNode(), authenticationv1.UserInfo{Username: "alice"})); !ar.Response.Allowed {
+		t.Fatalf("status error should fail open")
+	}
+}
+
+func TestValidateNode_Overrides(t *testing.T) {
+	alice := authenticationv1.UserInfo{Username: "alice"}
+	g := &TaintGuard{Status: fakeStatus{pending: []string{"x"}}, Allowed: []string{controllerSA}}
+
+	for _, key := range []string{startup.ReleaseAnnotation, startup.SkipAnnotation} {
+		annotated := taintedNode()
+		annotated.Annotations = map[string]string{key: "true"}
+		removed := untaintedNode()
+		removed.Annotations = map[string]string{key: "true"}
+		if ar := validate(g, updateReview(annotated, removed, alice)); !ar.Response.Allowed {
+			t.Fatalf("%s set beforehand should allow removal", key)
+		}
+		// Setting the override in the same update does not count.
+		if ar := validate(g, updateReview(taintedNode(), removed, alice)); ar.Response.Allowed {
+			t.Fatalf("%s added with the taint removal should be denied", key)
+		}
+	}
+
+	held := untaintedNode()
+	held.Annotations = map[string]string{startup.HoldAnnotation: "true"}
+	nothingPending := &TaintGuard{Status: fakeStatus{}, Allowed: []string{controllerSA}}
+	ar := validate(nothingPending, updateReview(taintedNode(), held, alice))
+	if ar.Response.Allowed || !strings.Contains(ar.Response.Result.Message, startup.HoldAnnotation) {
+		t.Fatalf("hold should deny removal even when ready, got %+v", ar.Response)
+	}
+	if ar := validate(nothingPending, updateReview(taintedNode(), held, authenticationv1.UserInfo{Username: controllerSA})); !ar.Response.Allowed {
+		t.Fatalf("allowlisted callers bypass hold")
+	}
+}
+
+func TestValidateNode_ConfiguredTaintKey(t *testing.T) {
+	if err := startup.SetTaintKey("ignore-taint.cluster-autoscaler.kubernetes.io/startup"); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = startup.SetTaintKey(startup.TaintKey) })
+
+	g := &TaintGuard{Status: fakeStatus{pending: []string{"x"}}}
+	alice := authenticationv1.UserInfo{Username: "alice"}
+	if ar := validate(g, updateReview(taintedNode(), untaintedNode(), alice)); ar.Response.Allowed {
+		t.Fatalf("removal of the configured startup taint should be denied")
+	}
+	legacy := &corev1.Node{
+		ObjectMeta: v1.ObjectMeta{Name: "n1"},
+		Spec:       corev1.NodeSpec{Taints: []corev1.Taint{{Key: startup.TaintKey, Value: startup.TaintValue, Effect: corev1.TaintEffectNoSchedule}}},
+	}
+	if ar := validate(g, updateReview(legacy, untaintedNode(), alice)); !ar.Response.Allowed {
+		t.Fatalf("removal of a taint with another key should be allowed")
+	}
+}
+
+func TestValidateNode_ManualRelease(t *testing.T) {
+	alice := authenticationv1.UserInfo{Username: "alice"}
+	for _, tc := range []struct {
+		name    string
+		allowed []string
+		admit   bool
+	}{{"allowlisted", []string{controllerSA, "alice"}, true}, {"not allowlisted", []string{controllerSA}, false}} {
+		t.Run(tc.name, func(t *testing.T) {
+			g := &TaintGuard{Status: fakeStatus{pending: []string{"x"}}, Allowed: tc.allowed}
+			old := taintedNode()
+			client := fake.NewSimpleClientset(old.DeepCopy())
+			// What `kubectl nodestartup release` sends.
+			if changed, err := startup.Release(context.TODO(), client, "n1", "alice"); err != nil || !changed {
+				t.Fatalf("release: changed=%v err=%v", changed, err)
+			}
+			released, _ := client.CoreV1().Nodes().Get(context.TODO(), "n1", v1.GetOptions{})
+			if ar := validate(g, updateReview(old, released, alice)); ar.Response.Allowed != tc.admit {
+				t.Fatalf("allowed=%v want %v: %+v", ar.Response.Allowed, tc.admit, ar.Response.Result)
+			}
+		})
+	}
+}
@@ -2,36 +2,58 @@ package webhook
 
 import (
 	"encoding/json"
+	"fmt"
 	"io"
 	"net/http"
 
 	admissionv1 "k8s.io/api/admission/v1"
 	corev1 "k8s.io/api/core/v1"
-	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/klog/v2"
 
+	"github.com/zhangchl007/nodetaintshandler/pkg/jsonpatch"
 	startup "github.com/zhangchl007/nodetaintshandler/pkg/startup"
 )
 
-type patchOp struct {
-	Op    string      `json:"op"`
-	Path  string      `json:"path"`
-	Value interface{} `json:"value,omitempty"`
+// TaintGate decides whether a new node may be gated (startup.Controller implements
+// it; a tripped fleet breaker says no).
+type TaintGate interface {
+	AllowTaint(node *corev1.Node) (bool, string)
 }
 
-const aksModeLabel = "kubernetes.azure.com/mode"
+// PolicySource picks the readiness policy (and so the taint) for a node
+// (startup.Controller implements it).
+type PolicySource interface {
+	PolicyFor(node *corev1.Node) startup.Policy
+}
+
+// NodeMutator taints new nodes. Gate is optional; without it every node is gated.
+// Policies is optional; without it every node gets startup.DefaultPolicy.
+type NodeMutator struct {
+	Gate     TaintGate
+	Policies PolicySource
+	// Version is recorded on tainted nodes (startup.NodeWebhookVersionAnnotation).
+	Version string
+	// OnError answers reviews that cannot be processed (default ErrorPolicyAllow).
+	OnError ErrorPolicy
+	// Limits bound each request served through RegisterMutator.
+	Limits Limits
+}
 
 // MutateNode adds the startup taint only on node CREATE if missing.
 func MutateNode(w http.ResponseWriter, r *http.Request) {
+	(&NodeMutator{}).MutateNode(w, r)
+}
+
+// MutateNode adds the startup taint only on node CREATE if missing and allowed by the gate.
+func (m *NodeMutator) MutateNode(w http.ResponseWriter, r *http.Request) {
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
-		http.Error(w, "read body error", http.StatusBadRequest)
+		writeError(w, admissionv1.AdmissionReview{}, m.OnError, errReadBody, http.StatusBadRequest, err)
 		return
 	}
-	review := admissionv1.AdmissionReview{}
-	if err := json.Unmarshal(body, &review); err != nil {
-		http.Error(w, "unmarshal error", http.StatusBadRequest)
+	review, err := readReview(body)
+	if err != nil {
+		writeError(w, review, m.OnError, errDecodeReview, http.StatusBadRequest, err)
 		return
 	}
 	if review.Request == nil || review.Request.Kind.Kind != "Node" {
@@ -46,93 +68,120 @@ func MutateNode(w http.ResponseWriter, r *http.Request) {
 
 	node := &corev1.Node{}
 	if err := json.Unmarshal(review.Request.Object.Raw, node); err != nil {
-		writeResponse(w, review, nil)
+		writeError(w, review, m.OnError, errDecodeObject, http.StatusBadRequest, fmt.Errorf("decode Node: %w", err))
 		return
 	}
 
-	if startup.HasStartupTaint(node) {
+	ov := startup.NodeOverride(node)
+	if ov.Ungated() {
+		klog.Infof("Skipping startup taint for node %s: %s=true", node.Name, ov.Annotation())
 		writeResponse(w, review, nil)
 		return
 	}
 
-	// AKS: skip system-mode nodes to avoid needing kube-system tolerations there
-	if val, ok := node.Labels[aksModeLabel]; ok && val == "system" {
+	// AKS: skip system-mode nodes to avoid needing kube-system tolerations there (unless held)
+	if startup.ExemptSystemNode(node) {
 		klog.Infof("Skipping startup taint for system-mode node %s", node.Name)
 		writeResponse(w, review, nil)
 		return
 	}
 
-	var ops []patchOp
-	if len(node.Spec.Taints) == 0 {
-		ops = append(ops, patchOp{
-			Op:   "add",
-			Path: "/spec/taints",
-			Value: []corev1.Taint{{
-				Key:    startup.TaintKey,
-				Value:  startup.TaintValue,
-				Effect: corev1.TaintEffectNoSchedule,
-			}},
-		})
-		klog.Infof("Adding startup taint to new node %s (no existing taints)", node.Name)
-	} else {
-		ops = append(ops, patchOp{
-			Op:   "add",
-			Path: "/spec/taints/-",
-			Value: corev1.Taint{
-				Key:    startup.TaintKey,
-				Value:  startup.TaintValue,
-				Effect: corev1.TaintEffectNoSchedule,
-			},
-		})
-		klog.Infof("Appending startup taint to node %s (existing taints=%d)", node.Name, len(node.Spec.Taints))
+	mutated := node.DeepCopy()
+	// Re-registration: a completion recorded on a previous Node object no longer applies.
+	if startup.CompletionStale(node) {
+		klog.Infof("Node %s re-registered with a stale completion annotation; gating again", node.Name)
+		delete(mutated.Annotations, startup.NodeStartupCompletedAnnotation)
+		delete(mutated.Annotations, startup.NodeStartupCompletedUIDAnnotation)
+	}
+
+	policy := startup.DefaultPolicy()
+	if m.Policies != nil {
+		policy = m.Policies.PolicyFor(node)
+	}
+	want := policy.Taint()
+	merged, sameKey, present := withStartupTaint(node.Spec.Taints, want)
+	allowed := true
+	if !present && m.Gate != nil && ov != startup.OverrideHold {
+		var reason string
+		if allowed, reason = m.Gate.AllowTaint(node); !allowed {
+			klog.Warningf("Not tainting new node %s: %s", node.Name, reason)
+		}
+	}
+	if !present && allowed {
+		// Same key with another value or effect (e.g. --register-with-taints), or
+		// duplicates, are collapsed into the policy's taint rather than added to.
+		mutated.Spec.Taints = merged
+		startup.MarkGating(mutated, policy.Name)
+		if m.Version != "" {
+			mutated.Annotations[startup.NodeWebhookVersionAnnotation] = m.Version
+		}
+		switch {
+		case sameKey > 0:
+			klog.Infof("Replacing %d %s taint(s) on node %s with the policy's startup taint", sameKey, want.Key, node.Name)
+		case len(node.Spec.Taints) == 0:
+			klog.Infof("Adding startup taint to new node %s (no existing taints)", node.Name)
+		default:
+			klog.Infof("Appending startup taint to node %s (existing taints=%d)", node.Name, len(node.Spec.Taints))
+		}
+	}
+
+	ops, err := jsonpatch.Diff(node, mutated)
+	if err != nil {
+		writeError(w, review, m.OnError, errBuildPatch, http.StatusInternalServerError, fmt.Errorf("diff node %s: %w", node.Name, err))
+		return
+	}
+	if len(ops) == 0 {
+		writeResponse(w, review, nil)
+		return
 	}
 	patchBytes, _ := json.Marshal(ops)
 	klog.Infof("Patch payload for node %s: %s", node.Name, string(patchBytes))
 	writePatch(w, review, patchBytes)
 }
 
-func writePatch(w http.ResponseWriter, in admissionv1.AdmissionReview, patch []byte) {
-	pt := admissionv1.PatchTypeJSONPatch
-	var uid types.UID
-	if in.Request != nil {
-		uid = in.Request.UID
+// withStartupTaint returns taints with every entry of want's key collapsed into
+// want (at the first entry's position, or appended), how many entries had the
+// key, and whether want was already the only one.
+func withStartupTaint(taints []corev1.Taint, want corev1.Taint) ([]corev1.Taint, int, bool) {
+	out := make([]corev1.Taint, 0, len(taints)+1)
+	sameKey, present := 0, false
+	for _, t := range taints {
+		if t.Key != want.Key {
+			out = append(out, t)
+			continue
+		}
+		if sameKey == 0 {
+			out = append(out, want)
+			present = t.Value == want.Value && t.Effect == want.Effect
+		}
+		sameKey++
 	}
-	resp := admissionv1.AdmissionReview{
-		TypeMeta: in.TypeMeta,
-		Response: &admissionv1.AdmissionResponse{
-			UID:       uid,
-			Allowed:   true,
-			Patch:     patch,
-			PatchType: &pt,
-		},
+	if sameKey == 0 {
+		out = append(out, want)
 	}
-	out, _ := json.Marshal(resp)
-	w.Header().Set("Content-Type", "application/json")
-	w.Write(out)
+	return out, sameKey, present && sameKey == 1
+}
+
+func writePatch(w http.ResponseWriter, in admissionv1.AdmissionReview, patch []byte) {
+	pt := admissionv1.PatchTypeJSONPatch
+	writeReview(w, in, &admissionv1.AdmissionResponse{
+		Allowed:   true,
+		Patch:     patch,
+		PatchType: &pt,
+	})
 }
 
 func writeResponse(w http.ResponseWriter, in admissionv1.AdmissionReview, _ []byte) {
-	var uid types.UID
-	if in.Request != nil {
-		uid = in.Request.UID
-	}
-	resp := admissionv1.AdmissionReview{
-		TypeMeta: v1.TypeMeta{
-			Kind:       "AdmissionReview",
-			APIVersion: "admission.k8s.io/v1",
-		},
-		Response: &admissionv1.AdmissionResponse{
-			UID:     uid,
-			Allowed: true,
-		},
-	}
-	out, _ := json.Marshal(resp)
-	w.Header().Set("Content-Type", "application/json")
-	w.Write(out)
+	writeReview(w, in, &admissionv1.AdmissionResponse{Allowed: true})
 }
 
 // Register registers handlers on a mux.
 func Register(mux *http.ServeMux) {
-	mux.HandleFunc("/mutate-node", MutateNode)
+	RegisterMutator(mux, &NodeMutator{})
+}
+
+// RegisterMutator registers the Node CREATE mutation handler of m on a mux.
+func RegisterMutator(mux *http.ServeMux, m *NodeMutator) {
+	mux.Handle("/mutate-node", admissionHandler(m.MutateNode, m.OnError, m.Limits))
 	klog.Info("Webhook handler registered (/mutate-node)")
 }
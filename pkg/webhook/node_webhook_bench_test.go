@@ -108,8 +108,8 @@ func BenchmarkMutateNode_InvalidJSON(b *testing.B) {
 		req := httptest.NewRequest(http.MethodPost, "/mutate-node", bytes.NewReader(body))
 		rr := httptest.NewRecorder()
 		MutateNode(rr, req)
-		if rr.Code != http.StatusBadRequest {
-			b.Fatalf("expected 400, got %d", rr.Code)
+		if rr.Code != http.StatusOK {
+			b.Fatalf("expected 200, got %d", rr.Code)
 		}
 	}
 }
@@ -5,13 +5,17 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
 	"testing"
+	"time"
 
+	evanphx "gopkg.in/evanphx/json-patch.v4"
 	admissionv1 "k8s.io/api/admission/v1"
 	corev1 "k8s.io/api/core/v1"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 
+	"github.com/zhangchl007/nodetaintshandler/pkg/jsonpatch"
 	startup "github.com/zhangchl007/nodetaintshandler/pkg/startup"
 )
 
@@ -67,18 +71,47 @@ func assertPatchNone(t *testing.T, ar admissionv1.AdmissionReview) {
 	}
 }
 
-func extractPatch(t *testing.T, ar admissionv1.AdmissionReview) []patchOp {
+func extractPatch(t *testing.T, ar admissionv1.AdmissionReview) []jsonpatch.Operation {
 	t.Helper()
 	if ar.Response == nil || len(ar.Response.Patch) == 0 {
 		t.Fatalf("no patch present")
 	}
-	var ops []patchOp
+	var ops []jsonpatch.Operation
 	if err := json.Unmarshal(ar.Response.Patch, &ops); err != nil {
 		t.Fatalf("unmarshal patch: %v", err)
 	}
 	return ops
 }
 
+// withoutAudit drops the ops recording who gated the node (see TestMutateNode_RecordsAudit).
+func withoutAudit(ops []jsonpatch.Operation) []jsonpatch.Operation {
+	audit := map[string]bool{
+		"/metadata/labels": true,
+		"/metadata/labels/" + jsonpatch.EscapePointer(startup.StateLabel): true,
+	}
+	for _, key := range []string{startup.NodeTaintedAtAnnotation, startup.NodePolicyAnnotation, startup.NodeWebhookVersionAnnotation} {
+		audit["/metadata/annotations/"+jsonpatch.EscapePointer(key)] = true
+	}
+	var out []jsonpatch.Operation
+	for _, op := range ops {
+		if op.Op == "add" && (audit[op.Path] || op.Path == "/metadata/annotations" && isAuditOnly(op.Value)) {
+			continue
+		}
+		out = append(out, op)
+	}
+	return out
+}
+
+func isAuditOnly(v interface{}) bool {
+	m, _ := v.(map[string]interface{})
+	for k := range m {
+		if k != startup.NodeTaintedAtAnnotation && k != startup.NodePolicyAnnotation && k != startup.NodeWebhookVersionAnnotation {
+			return false
+		}
+	}
+	return true
+}
+
 func TestMutateNode_AddsTaintWhenNoTaints(t *testing.T) {
 	node := &corev1.Node{
 		ObjectMeta: v1.ObjectMeta{Name: "n1"},
@@ -93,7 +126,7 @@ func TestMutateNode_AddsTaintWhenNoTaints(t *testing.T) {
 	if !ar.Response.Allowed {
 		t.Fatalf("expected Allowed true")
 	}
-	ops := extractPatch(t, ar)
+	ops := withoutAudit(extractPatch(t, ar))
 	if len(ops) != 1 {
 		t.Fatalf("expected 1 op, got %d", len(ops))
 	}
@@ -126,7 +159,7 @@ func TestMutateNode_AppendsTaintWhenExistingTaints(t *testing.T) {
 	body := buildAdmissionReview(node, admissionv1.Create, "Node")
 	rr := perform(body)
 	ar := decodeReview(t, rr)
-	ops := extractPatch(t, ar)
+	ops := withoutAudit(extractPatch(t, ar))
 	if len(ops) != 1 {
 		t.Fatalf("expected 1 op, got %d", len(ops))
 	}
@@ -189,7 +222,7 @@ func TestMutateNode_SkipsAKSSystemMode(t *testing.T) {
 	node := &corev1.Node{
 		ObjectMeta: v1.ObjectMeta{
 			Name:   "n5",
-			Labels: map[string]string{aksModeLabel: "system"},
+			Labels: map[string]string{startup.AKSModeLabel: "system"},
 		},
 	}
 	body := buildAdmissionReview(node, admissionv1.Create, "Node")
@@ -201,8 +234,15 @@ func TestMutateNode_InvalidBody(t *testing.T) {
 	req := httptest.NewRequest(http.MethodPost, "/mutate-node", bytes.NewBufferString("{not-json"))
 	rr := httptest.NewRecorder()
 	MutateNode(rr, req)
-	if rr.Code != http.StatusBadRequest {
-		t.Fatalf("expected 400, got %d", rr.Code)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected an AdmissionReview (200), got %d", rr.Code)
+	}
+	ar := decodeReview(t, rr)
+	if ar.Response == nil || !ar.Response.Allowed || ar.Response.Patch != nil {
+		t.Fatalf("expected unpatched allow by default, got %+v", ar.Response)
+	}
+	if ar.Response.Result == nil || ar.Response.Result.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 in Result, got %+v", ar.Response.Result)
 	}
 }
 
@@ -211,3 +251,219 @@ func TestMutateNode_RequestNil(t *testing.T) {
 	ar := decodeReview(t, rr)
 	assertPatchNone(t, ar)
 }
+
+func TestMutateNode_ReRegistrationStripsStaleCompletion(t *testing.T) {
+	node := &corev1.Node{
+		ObjectMeta: v1.ObjectMeta{
+			Name: "n6",
+			Annotations: map[string]string{
+				startup.NodeStartupCompletedAnnotation:    "1700000000",
+				startup.NodeStartupCompletedUIDAnnotation: "old-uid",
+				"keep": "me",
+			},
+		},
+	}
+	ar := decodeReview(t, perform(buildAdmissionReview(node, admissionv1.Create, "Node")))
+	ops := withoutAudit(extractPatch(t, ar))
+	want := map[string]string{
+		"/metadata/annotations/startup.k8s.io~1completedAt":  "remove",
+		"/metadata/annotations/startup.k8s.io~1completedUID": "remove",
+		"/spec/taints": "add",
+	}
+	if len(ops) != len(want) {
+		t.Fatalf("expected %d ops, got %+v", len(want), ops)
+	}
+	for _, op := range ops {
+		if want[op.Path] != op.Op {
+			t.Fatalf("unexpected op %+v", op)
+		}
+	}
+}
+
+func TestMutateNode_ReRegistrationWithTaintOnlyStripsAnnotation(t *testing.T) {
+	node := &corev1.Node{
+		ObjectMeta: v1.ObjectMeta{
+			Name:        "n7",
+			Annotations: map[string]string{startup.NodeStartupCompletedAnnotation: "1700000000"},
+		},
+		Spec: corev1.NodeSpec{Taints: []corev1.Taint{startup.StartupTaint}},
+	}
+	ops := extractPatch(t, decodeReview(t, perform(buildAdmissionReview(node, admissionv1.Create, "Node"))))
+	// The only annotation goes, so the patch drops the (now empty) map.
+	if len(ops) != 1 || ops[0].Op != "remove" || ops[0].Path != "/metadata/annotations" {
+		t.Fatalf("unexpected ops %+v", ops)
+	}
+	got := patchedNode(t, node, decodeReview(t, perform(buildAdmissionReview(node, admissionv1.Create, "Node"))))
+	if _, ok := got.Annotations[startup.NodeStartupCompletedAnnotation]; ok || !reflect.DeepEqual(got.Spec.Taints, node.Spec.Taints) {
+		t.Fatalf("unexpected node after patch: annotations=%v taints=%v", got.Annotations, got.Spec.Taints)
+	}
+}
+
+func TestMutateNode_OverrideAnnotations(t *testing.T) {
+	for _, key := range []string{startup.SkipAnnotation, startup.ReleaseAnnotation} {
+		node := &corev1.Node{ObjectMeta: v1.ObjectMeta{Name: "n7", Annotations: map[string]string{key: "true"}}}
+		assertPatchNone(t, decodeReview(t, perform(buildAdmissionReview(node, admissionv1.Create, "Node"))))
+	}
+
+	// Hold wins over skip and over the AKS system-mode exemption.
+	held := &corev1.Node{ObjectMeta: v1.ObjectMeta{
+		Name:        "n8",
+		Labels:      map[string]string{startup.AKSModeLabel: "system"},
+		Annotations: map[string]string{startup.HoldAnnotation: "true", startup.SkipAnnotation: "true"},
+	}}
+	ops := withoutAudit(extractPatch(t, decodeReview(t, perform(buildAdmissionReview(held, admissionv1.Create, "Node")))))
+	if len(ops) != 1 || ops[0].Path != "/spec/taints" {
+		t.Fatalf("expected taint added for held node, got %+v", ops)
+	}
+}
+
+type gateFunc func(*corev1.Node) (bool, string)
+
+func (f gateFunc) AllowTaint(n *corev1.Node) (bool, string) { return f(n) }
+
+func TestNodeMutator_TrippedGateSkipsTaint(t *testing.T) {
+	m := &NodeMutator{Gate: gateFunc(func(*corev1.Node) (bool, string) { return false, "tripped" })}
+	node := &corev1.Node{ObjectMeta: v1.ObjectMeta{Name: "n1"}}
+	req := httptest.NewRequest(http.MethodPost, "/mutate-node", bytes.NewReader(buildAdmissionReview(node, admissionv1.Create, "")))
+	rr := httptest.NewRecorder()
+	m.MutateNode(rr, req)
+	assertPatchNone(t, decodeReview(t, rr))
+
+	// A held node is still gated: hold is an explicit operator request.
+	node.Annotations = map[string]string{startup.HoldAnnotation: "true"}
+	req = httptest.NewRequest(http.MethodPost, "/mutate-node", bytes.NewReader(buildAdmissionReview(node, admissionv1.Create, "")))
+	rr = httptest.NewRecorder()
+	m.MutateNode(rr, req)
+	if ar := decodeReview(t, rr); ar.Response == nil || len(ar.Response.Patch) == 0 {
+		t.Fatalf("held node should still be tainted: %+v", ar.Response)
+	}
+}
+
+func TestMutateNode_UsesConfiguredTaintKey(t *testing.T) {
+	key := startup.ClusterAutoscalerIgnoreTaintPrefix + "startup"
+	if err := startup.SetTaintKey(key); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = startup.SetTaintKey(startup.TaintKey) })
+
+	node := &corev1.Node{ObjectMeta: v1.ObjectMeta{Name: "n1"}}
+	ops := withoutAudit(extractPatch(t, decodeReview(t, perform(buildAdmissionReview(node, admissionv1.Create, "Node")))))
+	valBytes, _ := json.Marshal(ops[0].Value)
+	var taints []corev1.Taint
+	if err := json.Unmarshal(valBytes, &taints); err != nil {
+		t.Fatalf("unmarshal taints: %v", err)
+	}
+	if len(taints) != 1 || taints[0].Key != key {
+		t.Fatalf("unexpected taints %+v", taints)
+	}
+}
+
+type policyFunc func(*corev1.Node) startup.Policy
+
+func (f policyFunc) PolicyFor(n *corev1.Node) startup.Policy { return f(n) }
+
+func TestNodeMutator_PolicyEffectReplacesSameKeyTaint(t *testing.T) {
+	noExecute := startup.StartupTaint
+	noExecute.Effect = corev1.TaintEffectNoExecute
+	m := &NodeMutator{Policies: policyFunc(func(*corev1.Node) startup.Policy {
+		return startup.Policy{Name: "evict", TaintEffect: corev1.TaintEffectNoExecute}
+	})}
+	node := &corev1.Node{
+		ObjectMeta: v1.ObjectMeta{Name: "n1"},
+		Spec: corev1.NodeSpec{Taints: []corev1.Taint{
+			{Key: "foo", Value: "bar", Effect: corev1.TaintEffectNoSchedule},
+			startup.StartupTaint,
+		}},
+	}
+	mutate := func() admissionv1.AdmissionReview {
+		req := httptest.NewRequest(http.MethodPost, "/mutate-node", bytes.NewReader(buildAdmissionReview(node, admissionv1.Create, "")))
+		rr := httptest.NewRecorder()
+		m.MutateNode(rr, req)
+		return decodeReview(t, rr)
+	}
+
+	want := []corev1.Taint{{Key: "foo", Value: "bar", Effect: corev1.TaintEffectNoSchedule}, noExecute}
+	if got := patchedNode(t, node, mutate()).Spec.Taints; !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected taints %+v", got)
+	}
+
+	// Already carrying the policy's taint: nothing to do.
+	node.Spec.Taints[1] = noExecute
+	assertPatchNone(t, mutate())
+}
+
+// patchedNode applies the response's patch to node, as the API server would.
+func patchedNode(t *testing.T, node *corev1.Node, ar admissionv1.AdmissionReview) *corev1.Node {
+	t.Helper()
+	if ar.Response == nil || len(ar.Response.Patch) == 0 {
+		t.Fatalf("no patch present")
+	}
+	raw, _ := json.Marshal(node)
+	patch, err := evanphx.DecodePatch(ar.Response.Patch)
+	if err != nil {
+		t.Fatalf("decode patch %s: %v", ar.Response.Patch, err)
+	}
+	out, err := patch.Apply(raw)
+	if err != nil {
+		t.Fatalf("apply patch %s: %v", ar.Response.Patch, err)
+	}
+	patched := &corev1.Node{}
+	if err := json.Unmarshal(out, patched); err != nil {
+		t.Fatal(err)
+	}
+	return patched
+}
+
+func TestMutateNode_ReplacesConflictingSameKeyTaints(t *testing.T) {
+	foo := corev1.Taint{Key: "foo", Value: "bar", Effect: corev1.TaintEffectNoSchedule}
+	baz := corev1.Taint{Key: "baz", Effect: corev1.TaintEffectNoExecute}
+	drifted := corev1.Taint{Key: startup.StartupTaint.Key, Value: "warming", Effect: corev1.TaintEffectNoSchedule}
+	otherEffect := corev1.Taint{Key: startup.StartupTaint.Key, Value: startup.TaintValue, Effect: corev1.TaintEffectPreferNoSchedule}
+	cases := map[string]struct {
+		in, want []corev1.Taint
+	}{
+		"drifted value":           {in: []corev1.Taint{drifted}, want: []corev1.Taint{startup.StartupTaint}},
+		"drifted value mixed":     {in: []corev1.Taint{foo, drifted, baz}, want: []corev1.Taint{foo, startup.StartupTaint, baz}},
+		"duplicate key":           {in: []corev1.Taint{startup.StartupTaint, foo, otherEffect}, want: []corev1.Taint{startup.StartupTaint, foo}},
+		"duplicate drifted first": {in: []corev1.Taint{otherEffect, foo, startup.StartupTaint, baz}, want: []corev1.Taint{startup.StartupTaint, foo, baz}},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			node := &corev1.Node{ObjectMeta: v1.ObjectMeta{Name: "n1"}, Spec: corev1.NodeSpec{Taints: tc.in}}
+			got := patchedNode(t, node, decodeReview(t, perform(buildAdmissionReview(node, admissionv1.Create, "Node")))).Spec.Taints
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Fatalf("got %+v, want %+v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestMutateNode_RecordsAudit(t *testing.T) {
+	m := &NodeMutator{Version: "v1.2.3", Policies: policyFunc(func(*corev1.Node) startup.Policy {
+		return startup.Policy{Name: "gpu"}
+	})}
+	node := &corev1.Node{ObjectMeta: v1.ObjectMeta{Name: "n1", Labels: map[string]string{"pool": "gpu"}}}
+	req := httptest.NewRequest(http.MethodPost, "/mutate-node", bytes.NewReader(buildAdmissionReview(node, admissionv1.Create, "")))
+	rr := httptest.NewRecorder()
+	before := time.Now().Add(-time.Second)
+	m.MutateNode(rr, req)
+	got := patchedNode(t, node, decodeReview(t, rr))
+
+	if got.Labels[startup.StateLabel] != startup.StateGating || got.Labels["pool"] != "gpu" {
+		t.Fatalf("unexpected labels %v", got.Labels)
+	}
+	if got.Annotations[startup.NodePolicyAnnotation] != "gpu" || got.Annotations[startup.NodeWebhookVersionAnnotation] != "v1.2.3" {
+		t.Fatalf("unexpected annotations %v", got.Annotations)
+	}
+	at, err := time.Parse(time.RFC3339, got.Annotations[startup.NodeTaintedAtAnnotation])
+	if err != nil || at.Before(before.Truncate(time.Second)) {
+		t.Fatalf("taintedAt=%q: %v", got.Annotations[startup.NodeTaintedAtAnnotation], err)
+	}
+
+	// Already tainted at registration: the webhook did not gate it, so records nothing.
+	node.Spec.Taints = []corev1.Taint{startup.StartupTaint}
+	req = httptest.NewRequest(http.MethodPost, "/mutate-node", bytes.NewReader(buildAdmissionReview(node, admissionv1.Create, "")))
+	rr = httptest.NewRecorder()
+	m.MutateNode(rr, req)
+	assertPatchNone(t, decodeReview(t, rr))
+}
@@ -0,0 +1,187 @@
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	admissionv1beta1 "k8s.io/api/admission/v1beta1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+	"k8s.io/apimachinery/pkg/types"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/klog/v2"
+)
+
+// ErrorPolicy decides the answer to a review the webhook cannot process.
+type ErrorPolicy string
+
+const (
+	// ErrorPolicyAllow admits the object unchanged, with a warning (the default).
+	ErrorPolicyAllow ErrorPolicy = "allow"
+	// ErrorPolicyDeny rejects the request with the error.
+	ErrorPolicyDeny ErrorPolicy = "deny"
+)
+
+// ParseErrorPolicy validates an --webhook-error-policy value.
+func ParseErrorPolicy(s string) (ErrorPolicy, error) {
+	switch p := ErrorPolicy(s); p {
+	case ErrorPolicyAllow, ErrorPolicyDeny:
+		return p, nil
+	}
+	return "", fmt.Errorf("invalid error policy %q (want %s or %s)", s, ErrorPolicyAllow, ErrorPolicyDeny)
+}
+
+// Where processing a review failed; recorded in the "error" audit annotation.
+const (
+	errReadBody     = "read-body"
+	errDecodeReview = "decode-review"
+	errDecodeObject = "decode-object"
+	errBuildPatch   = "build-patch"
+	errTimeout      = "timeout"
+	errPanic        = "panic"
+)
+
+var (
+	reviewScheme = runtime.NewScheme()
+	reviewCodecs = serializer.NewCodecFactory(reviewScheme)
+)
+
+func init() {
+	utilruntime.Must(admissionv1.AddToScheme(reviewScheme))
+	utilruntime.Must(admissionv1beta1.AddToScheme(reviewScheme))
+}
+
+// readReview decodes an AdmissionReview of a supported version (v1 or v1beta1)
+// into v1. The result keeps the request's apiVersion so the answer is written in
+// the same version; a review without apiVersion is taken as v1.
+//
+// On error the review still carries what could be recovered (version to answer
+// in, request UID) so the failure can be reported as an AdmissionResponse.
+func readReview(body []byte) (admissionv1.AdmissionReview, error) {
+	var tm v1.TypeMeta
+	if err := json.Unmarshal(body, &tm); err != nil {
+		return admissionv1.AdmissionReview{}, err
+	}
+	switch tm.APIVersion {
+	case "":
+		review := admissionv1.AdmissionReview{}
+		err := json.Unmarshal(body, &review)
+		review.TypeMeta = reviewTypeMeta(admissionv1.SchemeGroupVersion.String())
+		if err != nil {
+			return partialReview(body, review.APIVersion), err
+		}
+		return review, nil
+	case admissionv1.SchemeGroupVersion.String(), admissionv1beta1.SchemeGroupVersion.String():
+	default:
+		return partialReview(body, admissionv1.SchemeGroupVersion.String()), fmt.Errorf("unsupported AdmissionReview version %q (supported: %s, %s)",
+			tm.APIVersion, admissionv1.SchemeGroupVersion, admissionv1beta1.SchemeGroupVersion)
+	}
+
+	obj, gvk, err := reviewCodecs.UniversalDeserializer().Decode(body, nil, nil)
+	if err != nil {
+		return partialReview(body, tm.APIVersion), err
+	}
+	review := admissionv1.AdmissionReview{TypeMeta: reviewTypeMeta(gvk.GroupVersion().String())}
+	switch in := obj.(type) {
+	case *admissionv1.AdmissionReview:
+		review.Request = in.Request
+	case *admissionv1beta1.AdmissionReview:
+		if in.Request != nil {
+			// v1 is v1beta1 with defaults made explicit; the fields are identical.
+			review.Request = &admissionv1.AdmissionRequest{}
+			if err := convertVia(in.Request, review.Request); err != nil {
+				return admissionv1.AdmissionReview{}, fmt.Errorf("convert v1beta1 request: %w", err)
+			}
+		}
+	default:
+		return admissionv1.AdmissionReview{}, fmt.Errorf("unexpected %s, want AdmissionReview", gvk)
+	}
+	return review, nil
+}
+
+// writeReview answers in with resp, in the version the request was sent in.
+func writeReview(w http.ResponseWriter, in admissionv1.AdmissionReview, resp *admissionv1.AdmissionResponse) {
+	if in.Request != nil {
+		resp.UID = in.Request.UID
+	}
+	var out interface{} = admissionv1.AdmissionReview{TypeMeta: reviewTypeMeta(admissionv1.SchemeGroupVersion.String()), Response: resp}
+	if in.APIVersion == admissionv1beta1.SchemeGroupVersion.String() {
+		r := &admissionv1beta1.AdmissionResponse{}
+		if err := convertVia(resp, r); err != nil {
+			http.Error(w, fmt.Sprintf("convert v1beta1 response: %v", err), http.StatusInternalServerError)
+			return
+		}
+		out = admissionv1beta1.AdmissionReview{TypeMeta: reviewTypeMeta(in.APIVersion), Response: r}
+	}
+	b, _ := json.Marshal(out)
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(b)
+}
+
+// partialReview recovers the request UID from a review that failed to decode,
+// answering in apiVersion.
+func partialReview(body []byte, apiVersion string) admissionv1.AdmissionReview {
+	review := admissionv1.AdmissionReview{TypeMeta: reviewTypeMeta(apiVersion)}
+	var in struct {
+		Request *struct {
+			UID types.UID `json:"uid"`
+		} `json:"request"`
+	}
+	if json.Unmarshal(body, &in) == nil && in.Request != nil {
+		review.Request = &admissionv1.AdmissionRequest{UID: in.Request.UID}
+	}
+	return review
+}
+
+// writeError answers a review that could not be processed: allowed or denied by
+// policy, with the failure in Result, the audit annotations and (when allowed) a
+// warning, so it reaches the user instead of the apiserver's failurePolicy.
+func writeError(w http.ResponseWriter, in admissionv1.AdmissionReview, policy ErrorPolicy, stage string, code int32, err error) {
+	msg := fmt.Sprintf("%s: %v", stage, err)
+	var uid types.UID
+	if in.Request != nil {
+		uid = in.Request.UID
+	}
+	reason := v1.StatusReasonBadRequest
+	switch {
+	case code == http.StatusRequestEntityTooLarge:
+		reason = v1.StatusReasonRequestEntityTooLarge
+	case code == http.StatusGatewayTimeout:
+		reason = v1.StatusReasonTimeout
+	case code >= http.StatusInternalServerError:
+		reason = v1.StatusReasonInternalError
+	}
+	resp := &admissionv1.AdmissionResponse{
+		Allowed: policy != ErrorPolicyDeny,
+		Result: &v1.Status{
+			Status:  v1.StatusFailure,
+			Code:    code,
+			Reason:  reason,
+			Message: msg,
+		},
+		AuditAnnotations: map[string]string{"error": stage, "error-message": err.Error()},
+	}
+	if resp.Allowed {
+		resp.Warnings = []string{fmt.Sprintf("nodetaintshandler webhook: %s; allowed unchanged", msg)}
+		klog.Warningf("Allowing admission request %q unchanged: %s", uid, msg)
+	} else {
+		klog.Warningf("Denying admission request %q: %s", uid, msg)
+	}
+	writeReview(w, in, resp)
+}
+
+func reviewTypeMeta(apiVersion string) v1.TypeMeta {
+	return v1.TypeMeta{Kind: "AdmissionReview", APIVersion: apiVersion}
+}
+
+// convertVia converts between the wire-compatible v1 and v1beta1 admission types.
+func convertVia(in, out interface{}) error {
+	b, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, out)
+}
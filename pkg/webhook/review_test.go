@@ -0,0 +1,181 @@
+package webhook
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	admissionv1beta1 "k8s.io/api/admission/v1beta1"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	corev1 "k8s.io/api/core/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	startup "github.com/zhangchl007/nodetaintshandler/pkg/startup"
+)
+
+func versionedReview(t *testing.T, apiVersion string, node *corev1.Node) []byte {
+	t.Helper()
+	var ar map[string]interface{}
+	if err := json.Unmarshal(buildAdmissionReview(node, admissionv1.Create, "Node"), &ar); err != nil {
+		t.Fatal(err)
+	}
+	ar["apiVersion"] = apiVersion
+	ar["kind"] = "AdmissionReview"
+	b, _ := json.Marshal(ar)
+	return b
+}
+
+func TestMutateNode_ReviewVersions(t *testing.T) {
+	for _, version := range []string{"admission.k8s.io/v1", "admission.k8s.io/v1beta1"} {
+		t.Run(version, func(t *testing.T) {
+			node := &corev1.Node{ObjectMeta: v1.ObjectMeta{Name: "n1"}}
+			rr := perform(versionedReview(t, version, node))
+			if rr.Code != http.StatusOK {
+				t.Fatalf("status %d: %s", rr.Code, rr.Body.String())
+			}
+			var tm v1.TypeMeta
+			_ = json.Unmarshal(rr.Body.Bytes(), &tm)
+			if tm.APIVersion != version || tm.Kind != "AdmissionReview" {
+				t.Fatalf("answered in %s %s, want %s AdmissionReview", tm.APIVersion, tm.Kind, version)
+			}
+			ar := decodeReview(t, rr)
+			if ar.Response == nil || ar.Response.UID != "uid-123" || !ar.Response.Allowed {
+				t.Fatalf("unexpected response %+v", ar.Response)
+			}
+			if !startup.HasStartupTaint(patchedNode(t, node, ar)) {
+				t.Fatalf("expected startup taint in %s patch", version)
+			}
+		})
+	}
+}
+
+func TestMutateNode_ReviewV1beta1Decodes(t *testing.T) {
+	node := &corev1.Node{ObjectMeta: v1.ObjectMeta{Name: "n1"}}
+	review, err := readReview(versionedReview(t, "admission.k8s.io/v1beta1", node))
+	if err != nil {
+		t.Fatalf("readReview: %v", err)
+	}
+	if review.APIVersion != admissionv1beta1.SchemeGroupVersion.String() {
+		t.Fatalf("apiVersion %q not kept", review.APIVersion)
+	}
+	if review.Request == nil || review.Request.Operation != admissionv1.Create || review.Request.UID != "uid-123" {
+		t.Fatalf("request not converted: %+v", review.Request)
+	}
+}
+
+func TestMutateNode_UnknownReviewVersion(t *testing.T) {
+	node := &corev1.Node{ObjectMeta: v1.ObjectMeta{Name: "n1"}}
+	ar := decodeReview(t, perform(versionedReview(t, "admission.k8s.io/v2", node)))
+	if ar.APIVersion != "admission.k8s.io/v1" || ar.Response == nil || ar.Response.UID != "uid-123" {
+		t.Fatalf("expected a v1 answer for the request, got %+v", ar)
+	}
+	if ar.Response.Result == nil || !strings.Contains(ar.Response.Result.Message, `unsupported AdmissionReview version "admission.k8s.io/v2"`) {
+		t.Fatalf("unclear error: %+v", ar.Response.Result)
+	}
+}
+
+func TestValidateNode_DeniesInV1beta1(t *testing.T) {
+	var ar map[string]interface{}
+	_ = json.Unmarshal(updateReview(taintedNode(), untaintedNode(), authenticationv1.UserInfo{Username: "alice"}), &ar)
+	ar["apiVersion"] = "admission.k8s.io/v1beta1"
+	ar["kind"] = "AdmissionReview"
+	body, _ := json.Marshal(ar)
+
+	out := validate(&TaintGuard{Status: fakeStatus{pending: []string{"driver"}}}, body)
+	if out.APIVersion != "admission.k8s.io/v1beta1" {
+		t.Fatalf("answered in %q, want v1beta1", out.APIVersion)
+	}
+	if out.Response == nil || out.Response.Allowed {
+		t.Fatalf("expected denial, got %+v", out.Response)
+	}
+}
+
+type errReader struct{}
+
+func (errReader) Read([]byte) (int, error) { return 0, errors.New("connection reset") }
+
+func TestMutateNode_ErrorResponses(t *testing.T) {
+	node := &corev1.Node{ObjectMeta: v1.ObjectMeta{Name: "n1"}}
+	badNode, _ := json.Marshal(admissionv1.AdmissionReview{Request: &admissionv1.AdmissionRequest{
+		UID:       "uid-bad",
+		Kind:      v1.GroupVersionKind{Kind: "Node"},
+		Operation: admissionv1.Create,
+		Object:    runtimeRaw([]byte(`{"spec":{"taints":"not-a-list"}}`)),
+	}})
+	cases := []struct {
+		name  string
+		body  []byte
+		stage string
+		uid   types.UID
+	}{
+		{"read body", nil, errReadBody, ""},
+		{"malformed review", []byte("{not-json"), errDecodeReview, ""},
+		{"unsupported version", versionedReview(t, "admission.k8s.io/v2", node), errDecodeReview, "uid-123"},
+		{"malformed node", badNode, errDecodeObject, "uid-bad"},
+	}
+	for _, tc := range cases {
+		for _, policy := range []ErrorPolicy{ErrorPolicyAllow, ErrorPolicyDeny} {
+			t.Run(tc.name+"/"+string(policy), func(t *testing.T) {
+				var r io.Reader = bytes.NewReader(tc.body)
+				if tc.body == nil {
+					r = errReader{}
+				}
+				rr := httptest.NewRecorder()
+				(&NodeMutator{OnError: policy}).MutateNode(rr, httptest.NewRequest(http.MethodPost, "/mutate-node", r))
+				if rr.Code != http.StatusOK {
+					t.Fatalf("expected an AdmissionReview (200), got %d", rr.Code)
+				}
+				resp := decodeReview(t, rr).Response
+				if resp == nil || resp.UID != tc.uid {
+					t.Fatalf("expected response for uid %q, got %+v", tc.uid, resp)
+				}
+				if resp.Allowed != (policy == ErrorPolicyAllow) || resp.Patch != nil {
+					t.Fatalf("allowed=%v patch=%s under policy %s", resp.Allowed, resp.Patch, policy)
+				}
+				if resp.Result == nil || resp.Result.Code != http.StatusBadRequest || !strings.HasPrefix(resp.Result.Message, tc.stage+": ") {
+					t.Fatalf("unexpected result %+v", resp.Result)
+				}
+				if resp.AuditAnnotations["error"] != tc.stage {
+					t.Fatalf("audit annotations %v, want error=%s", resp.AuditAnnotations, tc.stage)
+				}
+				if (len(resp.Warnings) > 0) != resp.Allowed {
+					t.Fatalf("warnings %v with allowed=%v", resp.Warnings, resp.Allowed)
+				}
+			})
+		}
+	}
+}
+
+func TestValidateNode_MalformedNodeFollowsPolicy(t *testing.T) {
+	var ar admissionv1.AdmissionReview
+	_ = json.Unmarshal(updateReview(taintedNode(), untaintedNode(), authenticationv1.UserInfo{Username: "alice"}), &ar)
+	ar.Request.Object = runtimeRaw([]byte(`{"metadata":"bad"}`))
+	body, _ := json.Marshal(ar)
+	for _, policy := range []ErrorPolicy{ErrorPolicyAllow, ErrorPolicyDeny} {
+		out := validate(&TaintGuard{Status: fakeStatus{}, OnError: policy}, body)
+		if out.Response == nil || out.Response.Allowed != (policy == ErrorPolicyAllow) {
+			t.Fatalf("%s: unexpected response %+v", policy, out.Response)
+		}
+		if out.Response.AuditAnnotations["error"] != errDecodeObject {
+			t.Fatalf("%s: audit annotations %v", policy, out.Response.AuditAnnotations)
+		}
+	}
+}
+
+func TestParseErrorPolicy(t *testing.T) {
+	for _, s := range []string{"allow", "deny"} {
+		if p, err := ParseErrorPolicy(s); err != nil || string(p) != s {
+			t.Fatalf("ParseErrorPolicy(%q)=%q, %v", s, p, err)
+		}
+	}
+	if _, err := ParseErrorPolicy("ignore"); err == nil {
+		t.Fatalf("expected error for unknown policy")
+	}
+}